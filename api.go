@@ -1,9 +1,33 @@
 package remotetools
 
 import (
+	"context"
+
 	"github.com/kira1928/remotetools/pkg/tools"
+	"github.com/kira1928/remotetools/pkg/version"
 )
 
 func Get() *tools.API {
 	return tools.Get()
 }
+
+// WithAPI returns a copy of ctx carrying api, see tools.WithAPI.
+func WithAPI(ctx context.Context, api *tools.API) context.Context {
+	return tools.WithAPI(ctx, api)
+}
+
+// FromContext returns the API carried by ctx, or the default one.
+func FromContext(ctx context.Context) *tools.API {
+	return tools.FromContext(ctx)
+}
+
+// GetBuildInfo returns the version information injected at build time.
+func GetBuildInfo() version.BuildInfo {
+	return version.Get()
+}
+
+// GetModuleInfo returns the remotetools module version linked into the binary,
+// read from runtime/debug.ReadBuildInfo.
+func GetModuleInfo() (version.ModuleInfo, bool) {
+	return version.GetModuleInfo()
+}
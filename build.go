@@ -0,0 +1,66 @@
+//go:build ignore
+
+// build.go builds the CLI with the version information injected:
+//
+//	go run build.go [-o bin/remotetools] [-version v1.2.3] [-public-key RW...]
+//
+// The version defaults to `git describe --tags --always --dirty`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const versionPackage = "github.com/kira1928/remotetools/pkg/version"
+
+func main() {
+	defaultOutput := "bin/remotetools"
+	if runtime.GOOS == "windows" {
+		defaultOutput += ".exe"
+	}
+	output := flag.String("o", defaultOutput, "output file")
+	version := flag.String("version", "", "version to inject (default: git describe)")
+	publicKey := flag.String("public-key", "", "minisign public key self-update verifies releases with")
+	flag.Parse()
+
+	if *version == "" {
+		*version = git("describe", "--tags", "--always", "--dirty")
+	}
+	if *version == "" {
+		*version = "dev"
+	}
+	commit := git("rev-parse", "HEAD")
+	buildTime := time.Now().UTC().Format(time.RFC3339)
+
+	ldflags := strings.Join([]string{
+		"-X " + versionPackage + ".Version=" + *version,
+		"-X " + versionPackage + ".Commit=" + commit,
+		"-X " + versionPackage + ".BuildTime=" + buildTime,
+	}, " ")
+	if *publicKey != "" {
+		ldflags += " -X " + versionPackage + ".PublicKey=" + *publicKey
+	}
+
+	cmd := exec.Command("go", "build", "-ldflags", ldflags, "-o", *output, "./cmd")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "build failed:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("built %s %s (%s)\n", *output, *version, commit)
+}
+
+func git(args ...string) string {
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
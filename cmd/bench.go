@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += int64(len(b))
+	return len(b), nil
+}
+
+type benchResult struct {
+	version   string
+	durations []time.Duration
+	exitCodes []int
+	stdout    int64
+	stderr    int64
+	err       error
+}
+
+// remotetools bench <tool> [--versions a,b] [--runs n] -- <args>
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	versions := fs.String("versions", "", "comma separated versions to compare (default: all installed versions)")
+	runs := fs.Int("runs", 1, "number of runs per version")
+
+	positional, toolArgs := parseArgs(fs, args)
+	var toolName string
+	if len(positional) > 0 {
+		toolName = positional[0]
+	}
+	if len(positional) != 1 || *runs < 1 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools bench <tool> [--versions a,b] [--runs n] -- <args>")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+
+	var versionList []string
+	if *versions != "" {
+		versionList = strings.Split(*versions, ",")
+	} else {
+		installed, err := api.GetInstalledVersions(toolName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to list installed versions:", err)
+			return 1
+		}
+		versionList = installed
+	}
+	if len(versionList) == 0 {
+		fmt.Fprintf(os.Stderr, "no installed versions of %s to benchmark\n", toolName)
+		return 1
+	}
+
+	results := make([]benchResult, 0, len(versionList))
+	for _, version := range versionList {
+		results = append(results, benchVersion(api, toolName, strings.TrimSpace(version), toolArgs, *runs))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tRUNS\tAVG\tMIN\tMAX\tEXIT CODES\tSTDOUT\tSTDERR")
+	for _, result := range results {
+		if result.err != nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t%v\t-\t-\n", result.version, result.err)
+			continue
+		}
+		var total, min, max time.Duration
+		for i, d := range result.durations {
+			total += d
+			if i == 0 || d < min {
+				min = d
+			}
+			if d > max {
+				max = d
+			}
+		}
+		avg := total / time.Duration(len(result.durations))
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%v\t%d\t%d\n",
+			result.version, len(result.durations), avg.Round(time.Millisecond), min.Round(time.Millisecond),
+			max.Round(time.Millisecond), result.exitCodes, result.stdout/int64(len(result.durations)),
+			result.stderr/int64(len(result.durations)))
+	}
+	w.Flush()
+	return 0
+}
+
+func benchVersion(api *tools.API, toolName, version string, args []string, runs int) (result benchResult) {
+	result.version = version
+	tool, err := api.GetToolWithVersion(toolName, version)
+	if err != nil {
+		result.err = err
+		return
+	}
+
+	for i := 0; i < runs; i++ {
+		cmd, err := tool.CreateExecuteCmd(args...)
+		if err != nil {
+			result.err = err
+			return
+		}
+		stdout, stderr := &countingWriter{}, &countingWriter{}
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		startedAt := time.Now()
+		err = cmd.Run()
+		result.durations = append(result.durations, time.Since(startedAt))
+
+		exitCode := 0
+		if err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				result.err = err
+				return
+			}
+			exitCode = exitErr.ExitCode()
+		}
+		result.exitCodes = append(result.exitCodes, exitCode)
+		result.stdout += stdout.n
+		result.stderr += stderr.n
+	}
+	return
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+// remotetools export --tools <tool[@version]>[,...] -o <bundle.tar>
+// 把已安装的工具及其依赖打包，用于离线机器
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	var toolArgs stringList
+	fs.Var(&toolArgs, "tools", "tools to export, as tool or tool@version separated by commas (repeatable)")
+	out := fs.String("o", "", "bundle file to write, - for stdout")
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 0 || len(toolArgs) == 0 || *out == "" {
+		fmt.Fprintln(os.Stderr, "usage: remotetools export --tools <tool[@version]>[,...] -o <bundle.tar>")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	var exported []tools.Tool
+	for _, arg := range toolArgs {
+		for _, name := range strings.Split(arg, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			tool, err := getTool(api, name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			exported = append(exported, tool)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	manifest, err := api.Export(w, exported)
+	if err != nil {
+		if *out != "-" {
+			os.Remove(*out)
+		}
+		fmt.Fprintln(os.Stderr, "Failed to export:", err)
+		return 1
+	}
+	for _, entry := range manifest.Tools {
+		fmt.Fprintf(os.Stderr, "exported %s %s\n", entry.Tool, entry.Version)
+	}
+	return 0
+}
+
+// remotetools import <bundle.tar>
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools import <bundle.tar>")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	var r io.Reader = os.Stdin
+	if positional[0] != "-" {
+		f, err := os.Open(positional[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	results, err := api.Import(r)
+	for _, result := range results {
+		if result.Skipped {
+			fmt.Printf("%s %s is already installed, skipped\n", result.Tool, result.Version)
+		} else {
+			fmt.Printf("imported %s %s\n", result.Tool, result.Version)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to import:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// completeCommand 是补全脚本调用的隐藏子命令，打印配置中的工具名和 tool@version
+const completeCommand = "__complete"
+
+func init() {
+	// commands refers to runCompletion, which reads commands
+	commands = append(commands, command{"completion", "completion bash|zsh|fish|powershell", "print the shell completion script", runCompletion})
+}
+
+// remotetools completion bash|zsh|fish|powershell
+func runCompletion(args []string) int {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools completion bash|zsh|fish|powershell")
+		return 2
+	}
+
+	names := []string{"help"}
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	switch positional[0] {
+	case "bash":
+		fmt.Printf(bashCompletion, strings.Join(names, " "), completeCommand)
+	case "zsh":
+		fmt.Printf(zshCompletion, strings.Join(names, " "), completeCommand)
+	case "fish":
+		fmt.Println("complete -c remotetools -f")
+		for _, c := range commands {
+			fmt.Printf("complete -c remotetools -n __fish_use_subcommand -a %s -d %s\n", c.name, fishQuote(c.summary))
+		}
+		fmt.Printf("complete -c remotetools -n 'not __fish_use_subcommand' -a '(remotetools %s 2>/dev/null)'\n", completeCommand)
+	case "powershell":
+		fmt.Printf(powershellCompletion, "'"+strings.Join(names, "', '")+"'", completeCommand)
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported shell %q, use bash, zsh, fish or powershell\n", positional[0])
+		return 2
+	}
+	return 0
+}
+
+// remotetools __complete [common flags]
+// 打印每个工具名，以及已配置或已安装的 tool@version，每行一个
+func runComplete(args []string) int {
+	fs := flag.NewFlagSet(completeCommand, flag.ContinueOnError)
+	common := addCommonFlags(fs)
+	parseArgs(fs, args)
+	api, err := common.loadAPI()
+	if err != nil {
+		return 1
+	}
+
+	conf := api.GetConfig()
+	for _, toolName := range api.ToolNames() {
+		fmt.Println(toolName)
+		versions := make(map[string]bool)
+		for version := range conf.VersionConfigs[toolName] {
+			versions[version] = true
+		}
+		installed, _ := api.GetInstalledVersions(toolName)
+		for _, version := range installed {
+			versions[version] = true
+		}
+		sorted := make([]string, 0, len(versions))
+		for version := range versions {
+			sorted = append(sorted, version)
+		}
+		sort.Strings(sorted)
+		for _, version := range sorted {
+			fmt.Println(toolName + "@" + version)
+		}
+	}
+	return 0
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+const bashCompletion = `# bash completion for remotetools, load with: source <(remotetools completion bash)
+_remotetools() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    if [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur"))
+        return
+    fi
+    case "$cur" in
+    -*) return ;;
+    esac
+    COMPREPLY=($(compgen -W "$(remotetools %s 2>/dev/null)" -- "$cur"))
+}
+complete -o default -F _remotetools remotetools
+`
+
+const zshCompletion = `#compdef remotetools
+# zsh completion for remotetools, load with: source <(remotetools completion zsh)
+_remotetools() {
+    if (( CURRENT == 2 )); then
+        compadd -- %s
+    else
+        compadd -- ${(f)"$(remotetools %s 2>/dev/null)"}
+    fi
+}
+compdef _remotetools remotetools
+`
+
+const powershellCompletion = `# PowerShell completion for remotetools, load with:
+# remotetools completion powershell | Out-String | Invoke-Expression
+Register-ArgumentCompleter -Native -CommandName remotetools -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $elements = $commandAst.CommandElements
+    if ($elements.Count -eq 1 -or ($elements.Count -eq 2 -and $wordToComplete)) {
+        $candidates = @(%s)
+    } else {
+        $candidates = remotetools %s 2>$null
+    }
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
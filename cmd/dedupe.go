@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kira1928/remotetools/pkg/settings"
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+// remotetools dedupe [--json]
+// 把已安装的工具迁移到内容存储，之后的安装用 --dedupe 设置（REMOTETOOLS_DEDUPE=true）保持去重
+func runDedupe(args []string) int {
+	fs := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools dedupe [--json]")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	stats, err := api.Dedupe()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to dedupe:", err)
+		return 1
+	}
+	if *jsonOutput {
+		printJSON(stats)
+		return 0
+	}
+	fmt.Printf("%d files, %d linked, %s saved\n", stats.Files, stats.Linked, formatBytes(stats.SavedBytes))
+	if !tools.IsDedupeEnabled() {
+		fmt.Printf("set %s=true to dedupe new installs too\n", settings.EnvName(settings.KeyDedupe))
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+type doctorOutput struct {
+	Name       string `json:"name"`
+	Target     string `json:"target"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// remotetools doctor [--no-network] [--fix] [--json]
+// 有 error 级别的问题时返回 1
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	noNetwork := fs.Bool("no-network", false, "do not check whether the download urls can be reached")
+	fix := fs.Bool("fix", false, "remove the leftovers of interrupted installs")
+	jsonOutput := fs.Bool("json", false, "print the checks as a JSON array")
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools doctor [--no-network] [--fix] [--json]")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	checks := api.Doctor(context.Background(), tools.DoctorOptions{
+		SkipNetwork: *noNetwork,
+		Fix:         *fix,
+	})
+
+	failed := false
+	outputs := make([]doctorOutput, 0, len(checks))
+	for _, check := range checks {
+		failed = failed || check.Status == tools.DoctorError
+		outputs = append(outputs, doctorOutput(check))
+	}
+	if *jsonOutput {
+		printJSON(outputs)
+	} else {
+		for _, check := range checks {
+			fmt.Printf("[%s] %s %s: %s\n", strings.ToUpper(check.Status), check.Name, check.Target, check.Message)
+			if check.Suggestion != "" {
+				fmt.Printf("    suggestion: %s\n", check.Suggestion)
+			}
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
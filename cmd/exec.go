@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+// remotetools exec [--isolated-env] [--env K=V] [--dir DIR | --in-tool-folder] <tool>[@version] -- <args>
+func runExec(args []string) int {
+	fs := flag.NewFlagSet("exec", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	isolatedEnv := fs.Bool("isolated-env", false, "start the tool with a minimal whitelisted environment")
+	dir := fs.String("dir", "", "working directory of the tool, overrides the workingDir config field")
+	inToolFolder := fs.Bool("in-tool-folder", false, "start the tool from its install folder")
+	var envVars stringList
+	fs.Var(&envVars, "env", "set an environment variable for the tool, as KEY=VALUE (repeatable)")
+	positional, toolArgs := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools exec [--isolated-env] [--env K=V] [--dir DIR | --in-tool-folder] <tool>[@version] -- <args>")
+		return 2
+	}
+
+	opts := tools.ExecOptions{
+		IsolatedEnv:  *isolatedEnv,
+		Env:          make(map[string]string),
+		Dir:          *dir,
+		InToolFolder: *inToolFolder,
+	}
+	for _, kv := range envVars {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			fmt.Fprintf(os.Stderr, "invalid --env %q, expected KEY=VALUE\n", kv)
+			return 2
+		}
+		opts.Env[name] = value
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	tool, err := getTool(api, positional[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	cmd, err := tool.CreateExecuteCmdWithOptions(opts, toolArgs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to create command:", err)
+		return 1
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintln(os.Stderr, "Failed to execute command:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+// remotetools gc [--max-size 10GB] [--max-age 720h] [--keep tool[@version]] [--dry-run] [--json]
+// --max-size 也可以来自 REMOTETOOLS_MAX_SIZE 或设置文件
+func runGC(args []string) int {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	maxAge := fs.Duration("max-age", 0, "also remove versions not used for longer than this, e.g. 720h")
+	dryRun := fs.Bool("dry-run", false, "only print what would be removed")
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+	var keep stringList
+	fs.Var(&keep, "keep", "never remove a tool or tool@version (repeatable)")
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools gc [--max-size 10GB] [--max-age 720h] [--keep tool[@version]] [--dry-run] [--json]")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	policy := tools.GCPolicy{
+		MaxSize: tools.GetMaxToolFolderSize(),
+		MaxAge:  *maxAge,
+		Keep:    keep,
+		DryRun:  *dryRun,
+	}
+	if policy.MaxSize <= 0 && policy.MaxAge <= 0 {
+		fmt.Fprintln(os.Stderr, "nothing to do, set --max-size or --max-age")
+		return 2
+	}
+
+	report, err := api.GC(policy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to collect garbage:", err)
+		return 1
+	}
+	if *jsonOutput {
+		printJSON(report)
+		return 0
+	}
+	action := "removed"
+	if *dryRun {
+		action = "would remove"
+	}
+	for _, entry := range report.Removed {
+		fmt.Printf("%s %s %s (%s, last used %s)\n", action, entry.ToolName, entry.Version,
+			formatBytes(entry.Size), entry.LastUsed.Local().Format(time.RFC3339))
+	}
+	fmt.Printf("tool folder: %s -> %s\n", formatBytes(report.SizeBefore), formatBytes(report.SizeAfter))
+	return 0
+}
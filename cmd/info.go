@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+type toolInfo struct {
+	Name              string               `json:"name"`
+	Version           string               `json:"version"`
+	Installed         bool                 `json:"installed"`
+	Path              string               `json:"path"`
+	Folder            string               `json:"folder,omitempty"`
+	Scope             string               `json:"scope,omitempty"`
+	DownloadURL       string               `json:"downloadUrl,omitempty"`
+	PathToEntry       string               `json:"pathToEntry,omitempty"`
+	DevOverride       bool                 `json:"devOverride"`
+	DiskUsage         int64                `json:"diskUsage"`
+	InstalledVersions []string             `json:"installedVersions,omitempty"`
+	InstallRecord     *tools.InstallRecord `json:"installRecord,omitempty"`
+	Info              string               `json:"info,omitempty"`
+	InfoError         string               `json:"infoError,omitempty"`
+}
+
+// remotetools info <tool>[@version] [--json]
+func runInfo(args []string) int {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools info <tool>[@version] [--json]")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+
+	info, err := collectToolInfo(api, positional[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *jsonOutput {
+		printJSON(info)
+		return 0
+	}
+
+	fmt.Printf("Name:               %s\n", info.Name)
+	fmt.Printf("Version:            %s\n", info.Version)
+	fmt.Printf("Installed:          %t\n", info.Installed)
+	fmt.Printf("Path:               %s\n", info.Path)
+	if info.DevOverride {
+		fmt.Printf("Dev override:       %t\n", info.DevOverride)
+	}
+	if info.Folder != "" {
+		fmt.Printf("Folder:             %s\n", info.Folder)
+	}
+	if info.Scope != "" {
+		fmt.Printf("Scope:              %s\n", info.Scope)
+	}
+	if info.DownloadURL != "" {
+		fmt.Printf("Download URL:       %s\n", info.DownloadURL)
+	}
+	if info.PathToEntry != "" {
+		fmt.Printf("Path to entry:      %s\n", info.PathToEntry)
+	}
+	if info.Installed {
+		fmt.Printf("Disk usage:         %s\n", formatBytes(info.DiskUsage))
+	}
+	if len(info.InstalledVersions) > 0 {
+		fmt.Printf("Installed versions: %s\n", strings.Join(info.InstalledVersions, ", "))
+	}
+	if record := info.InstallRecord; record != nil {
+		fmt.Printf("Installed at:       %s\n", record.InstalledAt.Local().Format(time.RFC3339))
+		if record.InstalledBy != "" {
+			fmt.Printf("Installed by:       %s@%s\n", record.InstalledBy, record.Hostname)
+		}
+		fmt.Printf("Installed from:     %s\n", record.Source)
+		if record.SHA256 != "" {
+			fmt.Printf("Artifact sha256:    %s\n", record.SHA256)
+		}
+		if record.Commit != "" {
+			fmt.Printf("Commit:             %s\n", record.Commit)
+		}
+	}
+	if info.Info != "" {
+		fmt.Printf("\n%s", info.Info)
+	}
+	if info.InfoError != "" {
+		fmt.Printf("\nFailed to get tool info: %s\n", info.InfoError)
+	}
+	return 0
+}
+
+func collectToolInfo(api *tools.API, arg string) (info toolInfo, err error) {
+	toolName, _ := splitToolVersion(arg)
+	tool, err := getTool(api, arg)
+	if err != nil {
+		return
+	}
+
+	info = toolInfo{
+		Name:      toolName,
+		Version:   tool.GetVersion(),
+		Installed: tool.DoesToolExist(),
+		Path:      tool.GetToolPath(),
+	}
+	info.InstalledVersions, _ = api.GetInstalledVersions(toolName)
+	if _, ok := tool.(*tools.DevTool); ok {
+		info.DevOverride = true
+	}
+	conf := api.GetConfig()
+	toolConfig, configured := conf.GetVersionConfig(toolName, info.Version)
+	if !configured {
+		toolConfig, configured = conf.ToolConfigs[toolName]
+	}
+	if configured {
+		info.Scope = toolConfig.Scope
+		info.PathToEntry = toolConfig.Expand(toolConfig.PathToEntry.Value)
+		if toolConfig.Version == info.Version {
+			info.DownloadURL = toolConfig.Expand(toolConfig.DownloadURL.Value)
+		}
+	}
+	if folderTool, ok := tool.(interface{ GetToolFolder() string }); ok {
+		info.Folder = folderTool.GetToolFolder()
+	}
+	if !info.Installed {
+		return
+	}
+	if !info.DevOverride {
+		info.InstallRecord, _ = tool.GetInstallRecord()
+	}
+	if usageTool, ok := tool.(interface{ GetDiskUsage() (int64, error) }); ok {
+		info.DiskUsage, _ = usageTool.GetDiskUsage()
+	}
+	if infoTool, ok := tool.(interface{ ExecAndGetInfoString() (string, error) }); ok && configured && len(toolConfig.PrintInfoCmd) > 0 {
+		info.Info, err = infoTool.ExecAndGetInfoString()
+		if err != nil {
+			info.InfoError = err.Error()
+			err = nil
+		}
+	}
+	return
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
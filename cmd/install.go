@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+// remotetools install <tool> [--from <file|->] [--filename name]
+// remotetools install --all [--concurrency N]
+// 不带 --from 时从配置的地址下载；--from - 从标准输入读取安装包
+func runInstall(args []string) int {
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	from := fs.String("from", "", "install from a local artifact instead of downloading, - reads stdin")
+	filename := fs.String("filename", "", "artifact file name used to detect the format, defaults to the name in --from or the download URL")
+	all := fs.Bool("all", false, "install every configured tool that is not installed yet")
+	concurrency := fs.Int("concurrency", 0, "number of tools installed at the same time with --all, 4 by default")
+	jsonOutput := fs.Bool("json", false, "print the result as JSON, an array with --all")
+	noProgress := fs.Bool("no-progress", false, "do not print the download progress to stderr")
+	positional, _ := parseArgs(fs, args)
+	if *all && len(positional) != 0 || !*all && len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools install <tool> [--from <file|->] [--filename name] [--json] [--no-progress]")
+		fmt.Fprintln(os.Stderr, "       remotetools install --all [--concurrency N] [--json] [--no-progress]")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	var callback tools.ProgressCallback
+	finishProgress := func() {}
+	if !*noProgress {
+		printer := newProgressPrinter(os.Stderr, *all)
+		callback, finishProgress = printer.callback, printer.done
+	}
+	if *all {
+		return installAll(api, *concurrency, *jsonOutput, callback)
+	}
+	tool, err := getTool(api, positional[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	toolName, version := splitToolVersion(positional[0])
+	result := tools.InstallResult{
+		ToolName: toolName,
+		Version:  tool.GetVersion(),
+		Status:   tools.InstallStatusInstalled,
+	}
+	if tool.DoesToolExist() {
+		result.Status = tools.InstallStatusSkipped
+	}
+	startedAt := time.Now()
+	if *from == "" {
+		// API.Install also installs the dependencies
+		err = api.Install(context.Background(), toolName, version, callback)
+	} else {
+		err = installFrom(tool, *from, *filename)
+	}
+	result.Duration = time.Since(startedAt)
+	finishProgress()
+	if err != nil {
+		result.Status, result.Err = tools.InstallStatusFailed, err
+	}
+
+	if *jsonOutput {
+		printJSON(newInstallOutput(result))
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to install %s: %v\n", positional[0], err)
+	} else {
+		fmt.Printf("%s %s installed\n", positional[0], tool.GetVersion())
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+type installOutput struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// installed, skipped or failed
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+func newInstallOutput(result tools.InstallResult) installOutput {
+	output := installOutput{
+		Name:       result.ToolName,
+		Version:    result.Version,
+		Status:     result.Status,
+		DurationMs: result.Duration.Milliseconds(),
+	}
+	if result.Err != nil {
+		output.Error = result.Err.Error()
+	}
+	return output
+}
+
+func installAll(api *tools.API, concurrency int, jsonOutput bool, callback tools.ProgressCallback) int {
+	results, err := api.InstallAll(context.Background(), tools.InstallAllOptions{
+		Concurrency: concurrency,
+		Callback:    callback,
+	})
+	if jsonOutput {
+		outputs := make([]installOutput, 0, len(results))
+		for _, result := range results {
+			outputs = append(outputs, newInstallOutput(result))
+		}
+		printJSON(outputs)
+		if err != nil {
+			return 1
+		}
+		return 0
+	}
+	for _, result := range results {
+		switch result.Status {
+		case tools.InstallStatusFailed:
+			fmt.Printf("%-20s %-12s failed: %v\n", result.ToolName, result.Version, result.Err)
+		case tools.InstallStatusInstalled:
+			fmt.Printf("%-20s %-12s installed in %s\n", result.ToolName, result.Version, result.Duration.Round(time.Millisecond))
+		default:
+			fmt.Printf("%-20s %-12s %s\n", result.ToolName, result.Version, result.Status)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func installFrom(tool tools.Tool, from, filename string) (err error) {
+	if from != "-" && filename == "" {
+		return tool.InstallFromArchive(from)
+	}
+
+	installer, ok := tool.(interface {
+		InstallFromReader(r io.Reader, filename string) error
+	})
+	if !ok {
+		return fmt.Errorf("tool does not support installing from a stream")
+	}
+
+	var r io.Reader
+	if from == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(from)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if filename == "" {
+		downloadURL, _ := tool.GetDownloadURLForPlatform(runtime.GOOS, runtime.GOARCH)
+		parsedURL, err := url.Parse(downloadURL)
+		if err != nil || downloadURL == "" {
+			return fmt.Errorf("--filename is required when reading from stdin")
+		}
+		filename = path.Base(parsedURL.Path)
+	}
+	return installer.InstallFromReader(r, filename)
+}
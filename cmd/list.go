@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type listEntry struct {
+	Name string `json:"name"`
+	// the version GetTool returns, after lockfile and pins
+	Version           string   `json:"version"`
+	InstalledVersions []string `json:"installedVersions"`
+}
+
+// remotetools list [--installed]
+func runList(args []string) int {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	installedOnly := fs.Bool("installed", false, "only list the tools with an installed version")
+	jsonOutput := fs.Bool("json", false, "print the tools as a JSON array")
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools list [--installed] [--json]")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	entries := []listEntry{}
+	for _, toolName := range api.ToolNames() {
+		entry := listEntry{Name: toolName, InstalledVersions: []string{}}
+		if tool, err := api.GetTool(toolName); err == nil && tool != nil {
+			entry.Version = tool.GetVersion()
+		}
+		if installed, _ := api.GetInstalledVersions(toolName); len(installed) > 0 {
+			entry.InstalledVersions = installed
+		}
+		if *installedOnly && len(entry.InstalledVersions) == 0 {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if *jsonOutput {
+		printJSON(entries)
+		return 0
+	}
+	for _, entry := range entries {
+		installedText := "-"
+		if len(entry.InstalledVersions) > 0 {
+			installedText = strings.Join(entry.InstalledVersions, ", ")
+		}
+		fmt.Printf("%-20s %-12s installed: %s\n", entry.Name, entry.Version, installedText)
+	}
+	return 0
+}
@@ -1,19 +1,114 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/kira1928/remotetools/pkg/tools"
 )
 
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) int
+}
+
+// commands 按帮助中显示的顺序排列，每个子命令的参数见其 --help
+var commands = []command{
+	{"install", "install <tool>[@version] | --all", "install a tool and its dependencies, or every configured tool", runInstall},
+	{"uninstall", "uninstall <tool>[@version]", "remove an installed version", runUninstall},
+	{"list", "list [--installed]", "list the configured tools and their installed versions", runList},
+	{"exec", "exec <tool>[@version] -- <args>", "run an installed tool", runExec},
+	{"which", "which <tool>[@version]", "print the file that is run for a tool, dev overrides included", runWhich},
+	{"info", "info <tool>[@version]", "print the config and install record of a tool", runInfo},
+	{"check", "check <tool>[@version]", "report whether a tool is installed, exit with 1 when it is not", runCheck},
+	{"get-version", "get-version <tool>[@version]", "print the version of a tool", runGetVersion},
+	{"get-path", "get-path <tool>[@version]", "print the entry path of a tool", runGetPath},
+	{"doctor", "doctor [--no-network] [--fix]", "check the install folders, leftovers and download urls", runDoctor},
+	{"shims", "shims <dir>", "write launchers of the installed tools into a folder for PATH", runShims},
+	{"mirror", "mirror --dest <dir> [tool...]", "download the artifacts of tools into a mirror folder", runMirror},
+	{"package", "package <dir> --name <tool> --version <version>", "pack a folder as a tool artifact", runPackage},
+	{"verify", "verify [tool[@version]...]", "check installed files against their install manifest", runVerify},
+	{"repair", "repair [tool[@version]...]", "replace missing or modified files of installed tools", runRepair},
+	{"gc", "gc [--max-size 10GB] [--max-age 720h] [--keep tool[@version]] [--dry-run]", "remove the least recently used versions", runGC},
+	{"dedupe", "dedupe [--json]", "hard link identical files of the installed tools", runDedupe},
+	{"export", "export --tools <tool[@version]>[,...] -o <bundle.tar>", "pack installed tools for machines without network access", runExport},
+	{"import", "import <bundle.tar>", "install the tools of a bundle written by export", runImport},
+	{"bench", "bench <tool> [--versions a,b] [--runs n] -- <args>", "compare the run time of installed versions of a tool", runBench},
+	{"self-update", "self-update [--check]", "replace this binary with the latest release", runSelfUpdate},
+	{"version", "version", "print the version of remotetools", runVersion},
+	{"demo", "demo", "install dotnet and print its info", func(args []string) int {
+		runDemo(args)
+		return 0
+	}},
+}
+
 func main() {
-	err := tools.Get().LoadConfig("config/sample.json")
+	if len(os.Args) < 2 {
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+	name, args := os.Args[1], os.Args[2:]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		os.Exit(runHelp(args))
+	case completeCommand:
+		os.Exit(runComplete(args))
+	}
+	for _, c := range commands {
+		if c.name == name {
+			os.Exit(c.run(args))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	printUsage(os.Stderr)
+	os.Exit(2)
+}
+
+// remotetools help [command]
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return 0
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			// the flag set of the command prints its flags
+			return c.run([]string{"-h"})
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: remotetools <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+		fmt.Fprintf(w, "  %-12s   remotetools %s\n", "", c.usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run \"remotetools help <command>\" for the flags of a command")
+}
+
+func runDemo(args []string) {
+	fs := flag.NewFlagSet("remotetools", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	fs.Parse(args)
+
+	api, err := common.loadAPI()
 	if err != nil {
 		fmt.Println("Failed to load config:", err)
 		return
 	}
-	dotnet, err := tools.Get().GetTool("dotnet")
+	dotnet, err := api.GetTool("dotnet")
 	if err != nil {
 		fmt.Println("Failed to get tool:", err)
 		return
@@ -40,3 +135,49 @@ func main() {
 		return
 	}
 }
+
+// printJSON 是 --json 的输出格式，字段名一经发布不再改动
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// splitToolVersion splits "tool@version" into its parts, version is empty without "@".
+func splitToolVersion(arg string) (toolName, version string) {
+	toolName, version, _ = strings.Cut(arg, "@")
+	return
+}
+
+// getTool resolves "tool" or "tool@version".
+func getTool(api *tools.API, arg string) (tool tools.Tool, err error) {
+	toolName, version := splitToolVersion(arg)
+	if version != "" {
+		return api.GetToolWithVersion(toolName, version)
+	}
+	tool, err = api.GetTool(toolName)
+	if err == nil && tool == nil {
+		err = fmt.Errorf("tool %s not found in config", toolName)
+	}
+	return
+}
+
+// parseArgs parses flags mixed with positional arguments. Everything after
+// "--" is returned untouched as passthrough.
+func parseArgs(fs *flag.FlagSet, args []string) (positional []string, passthrough []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			args, passthrough = args[:i], args[i+1:]
+			break
+		}
+	}
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+// remotetools mirror --dest <dir> [--all-platforms] [tool...]
+// 下载配置中的安装包到本地目录，供离线分发或作为本地镜像使用
+func runMirror(args []string) int {
+	fs := flag.NewFlagSet("mirror", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	dest := fs.String("dest", "", "folder to download the artifacts to")
+	allPlatforms := fs.Bool("all-platforms", false, "download the artifacts of every known os/arch, not only the current one")
+	positional, _ := parseArgs(fs, args)
+	if *dest == "" {
+		fmt.Fprintln(os.Stderr, "usage: remotetools mirror --dest <dir> [--all-platforms] [tool...]")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+
+	platforms := []tools.Platform{tools.CurrentPlatform()}
+	if *allPlatforms {
+		platforms = tools.KnownPlatforms
+	}
+	index, err := api.Mirror(context.Background(), *dest, platforms, positional)
+	for _, entry := range index.Entries {
+		fmt.Printf("%s %s %s\t%s\n", entry.Tool, entry.Version, entry.Platform, entry.Path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to mirror:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+type packageSnippet struct {
+	Version     string                       `json:"version"`
+	DownloadURL map[string]map[string]string `json:"downloadUrl"`
+	ChecksumURL map[string]map[string]string `json:"checksumUrl"`
+	SHA256      map[string]map[string]string `json:"sha256"`
+	PathToEntry string                       `json:"pathToEntry,omitempty"`
+}
+
+// remotetools package <dir> --name <tool> --version <version> [--entry path] [--format zip|tar.gz]
+// 打包一个本地目录，同时生成 .sha256 文件并打印可直接粘贴的配置片段
+func runPackage(args []string) int {
+	fs := flag.NewFlagSet("package", flag.ExitOnError)
+	name := fs.String("name", "", "tool name")
+	version := fs.String("version", "", "tool version")
+	entry := fs.String("entry", "", "path of the executable inside the folder, detected from --name by default")
+	format := fs.String("format", "zip", "archive format, zip or tar.gz")
+	goos := fs.String("os", runtime.GOOS, "platform the folder was built for")
+	goarch := fs.String("arch", runtime.GOARCH, "architecture the folder was built for")
+	out := fs.String("out", "", "output file (default \"<name>-<version>-<os>-<arch>.<format>\")")
+	baseURL := fs.String("base-url", "https://example.com/tools", "URL the artifact will be published under, used in the config snippet")
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 1 || *name == "" || *version == "" {
+		fmt.Fprintln(os.Stderr, "usage: remotetools package <dir> --name <tool> --version <version> [--entry path] [--format zip|tar.gz]")
+		return 2
+	}
+	if *format != "zip" && *format != "tar.gz" {
+		fmt.Fprintf(os.Stderr, "unsupported format %q, use zip or tar.gz\n", *format)
+		return 2
+	}
+	dir := positional[0]
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "%s is not a folder\n", dir)
+		return 1
+	}
+
+	pathToEntry := filepath.ToSlash(*entry)
+	if pathToEntry == "" {
+		pathToEntry = findPackageEntry(dir, *name, *goos)
+		if pathToEntry == "" {
+			fmt.Fprintln(os.Stderr, "warning: no executable found, use --entry to set pathToEntry")
+		}
+	} else if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(pathToEntry))); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	dest := *out
+	if dest == "" {
+		dest = fmt.Sprintf("%s-%s-%s-%s.%s", *name, *version, *goos, *goarch, *format)
+	}
+	checksum, err := tools.PackageFolder(dir, dest, pathToEntry)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to package:", err)
+		return 1
+	}
+	fileName := filepath.Base(dest)
+	if err = os.WriteFile(dest+".sha256", []byte(checksum+"  "+fileName+"\n"), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	downloadURL := strings.TrimSuffix(*baseURL, "/") + "/" + fileName
+	snippet := map[string]packageSnippet{
+		*name: {
+			Version:     *version,
+			DownloadURL: map[string]map[string]string{*goos: {*goarch: downloadURL}},
+			ChecksumURL: map[string]map[string]string{*goos: {*goarch: downloadURL + ".sha256"}},
+			SHA256:      map[string]map[string]string{*goos: {*goarch: checksum}},
+			PathToEntry: pathToEntry,
+		},
+	}
+
+	fmt.Fprintf(os.Stderr, "created %s\nsha256: %s\n", dest, checksum)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(snippet)
+	return 0
+}
+
+// findPackageEntry 在目录中查找名为 name（Windows 下为 name.exe）的文件，取层级最浅的一个
+func findPackageEntry(dir, name, goos string) (entry string) {
+	if goos == "windows" {
+		name += ".exe"
+	}
+	depth := -1
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Name() != name {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d := strings.Count(rel, "/"); depth < 0 || d < depth {
+			entry, depth = rel, d
+		}
+		return nil
+	})
+	return
+}
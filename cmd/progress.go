@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+const progressBarWidth = 30
+
+// progressPrinter 把安装进度输出到终端：单个安装时在同一行刷新进度条，
+// 输出不是终端或多个工具同时安装时只在阶段变化时打印一行
+type progressPrinter struct {
+	lock     sync.Mutex
+	w        io.Writer
+	bar      bool
+	current  string
+	phases   map[string]string
+	lineSize int
+}
+
+func newProgressPrinter(w *os.File, concurrent bool) *progressPrinter {
+	return &progressPrinter{
+		w:      w,
+		bar:    !concurrent && isTerminal(w),
+		phases: make(map[string]string),
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *progressPrinter) callback(progress tools.DownloadProgress) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	key := progress.ToolName + " " + progress.Version
+	if !p.bar {
+		if p.phases[key] != progress.Phase {
+			p.phases[key] = progress.Phase
+			fmt.Fprintln(p.w, describePhase(key, progress))
+		}
+		return
+	}
+
+	// a dependency installed before the tool gets its own line
+	if p.current != "" && p.current != key {
+		p.finishLine()
+	}
+	p.current = key
+	var line string
+	if progress.Phase == tools.PhaseDownloading {
+		line = key + " " + formatDownload(progress)
+	} else {
+		line = describePhase(key, progress)
+	}
+	p.printLine(line)
+	if progress.Phase == tools.PhaseCompleted {
+		p.finishLine()
+	}
+}
+
+func (p *progressPrinter) printLine(line string) {
+	padding := ""
+	if n := p.lineSize - len(line); n > 0 {
+		padding = strings.Repeat(" ", n)
+	}
+	fmt.Fprint(p.w, "\r"+line+padding)
+	p.lineSize = len(line)
+}
+
+func (p *progressPrinter) finishLine() {
+	if p.lineSize > 0 {
+		fmt.Fprintln(p.w)
+	}
+	p.current, p.lineSize = "", 0
+}
+
+// done ends the progress line, e.g. when an install fails half way.
+func (p *progressPrinter) done() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.bar {
+		p.finishLine()
+	}
+}
+
+func describePhase(key string, progress tools.DownloadProgress) string {
+	switch progress.Phase {
+	case tools.PhaseDownloading:
+		if progress.URL != "" {
+			return fmt.Sprintf("%s downloading from %s", key, progress.URL)
+		}
+		return key + " downloading"
+	case tools.PhaseVerifying:
+		return key + " verifying"
+	case tools.PhaseExtracting:
+		return key + " extracting"
+	case tools.PhaseCompleted:
+		return key + " completed"
+	}
+	return key + " " + progress.Phase
+}
+
+// formatDownload 形如 [=====>    ]  45% 12.3 MiB/27.0 MiB 2.1 MiB/s ETA 7s，总大小未知时没有进度条
+func formatDownload(progress tools.DownloadProgress) string {
+	speed := formatBytes(int64(progress.Speed)) + "/s"
+	if progress.TotalBytes <= 0 {
+		return fmt.Sprintf("%s %s", formatBytes(progress.DownloadedBytes), speed)
+	}
+
+	ratio := float64(progress.DownloadedBytes) / float64(progress.TotalBytes)
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	eta := "--"
+	if progress.Speed > 0 {
+		remaining := float64(progress.TotalBytes-progress.DownloadedBytes) / progress.Speed
+		eta = (time.Duration(remaining) * time.Second).String()
+	}
+	return fmt.Sprintf("[%s] %3.0f%% %s/%s %s ETA %s", bar, ratio*100,
+		formatBytes(progress.DownloadedBytes), formatBytes(progress.TotalBytes), speed, eta)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+type toolStatus struct {
+	// the argument as given, tool or tool@version
+	Tool      string `json:"tool"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Installed bool   `json:"installed"`
+	Path      string `json:"path"`
+}
+
+func newToolStatus(arg string, tool tools.Tool) toolStatus {
+	path := tool.GetToolPath()
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	toolName, _ := splitToolVersion(arg)
+	return toolStatus{
+		Tool:      arg,
+		Name:      toolName,
+		Version:   tool.GetVersion(),
+		Installed: tool.DoesToolExist(),
+		Path:      path,
+	}
+}
+
+// queryCommand 是 check/get-version/get-path 共用的实现，--porcelain 时只打印原始值
+func queryCommand(name string, args []string, query func(arg string, tool tools.Tool) (value string, message string, ok bool)) int {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	common := addCommonFlags(fs)
+	porcelain := fs.Bool("porcelain", false, "print only the raw value, for scripts")
+	jsonOutput := fs.Bool("json", false, "print the tool as JSON, the same object for check, get-version and get-path")
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: remotetools %s [--porcelain | --json] <tool>[@version]\n", name)
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	tool, err := getTool(api, positional[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	value, message, ok := query(positional[0], tool)
+	if *jsonOutput {
+		printJSON(newToolStatus(positional[0], tool))
+	} else if *porcelain {
+		fmt.Println(value)
+	} else {
+		fmt.Println(message)
+	}
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+// remotetools check <tool>[@version]
+func runCheck(args []string) int {
+	return queryCommand("check", args, func(arg string, tool tools.Tool) (string, string, bool) {
+		if tool.DoesToolExist() {
+			return "true", fmt.Sprintf("%s %s is installed", arg, tool.GetVersion()), true
+		}
+		return "false", fmt.Sprintf("%s %s is not installed", arg, tool.GetVersion()), false
+	})
+}
+
+// remotetools get-version <tool>[@version]
+func runGetVersion(args []string) int {
+	return queryCommand("get-version", args, func(arg string, tool tools.Tool) (string, string, bool) {
+		return tool.GetVersion(), fmt.Sprintf("%s version: %s", arg, tool.GetVersion()), true
+	})
+}
+
+// remotetools get-path <tool>[@version]
+func runGetPath(args []string) int {
+	return queryCommand("get-path", args, func(arg string, tool tools.Tool) (string, string, bool) {
+		path := tool.GetToolPath()
+		if absPath, err := filepath.Abs(path); err == nil {
+			path = absPath
+		}
+		return path, fmt.Sprintf("%s path: %s", arg, path), true
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+type repairEntry struct {
+	Tool    string `json:"tool"`
+	Version string `json:"version"`
+	tools.RepairResult
+	Error string `json:"error,omitempty"`
+}
+
+// remotetools repair [tool[@version]...] [--json] [--no-progress]
+// 不指定工具时修复所有已安装的版本
+func runRepair(args []string) int {
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+	noProgress := fs.Bool("no-progress", false, "do not print the download progress to stderr")
+	positional, _ := parseArgs(fs, args)
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	var targets []tools.Tool
+	if len(positional) == 0 {
+		for _, toolName := range api.ToolNames() {
+			versions, _ := api.GetInstalledVersions(toolName)
+			for _, version := range versions {
+				if tool, err := api.GetToolWithVersion(toolName, version); err == nil {
+					targets = append(targets, tool)
+				}
+			}
+		}
+	}
+	for _, arg := range positional {
+		tool, err := getTool(api, arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		targets = append(targets, tool)
+	}
+
+	exitCode := 0
+	entries := []repairEntry{}
+	for _, tool := range targets {
+		var callback tools.ProgressCallback
+		finishProgress := func() {}
+		if !*noProgress {
+			printer := newProgressPrinter(os.Stderr, false)
+			callback, finishProgress = printer.callback, printer.done
+		}
+		entry := repairEntry{Tool: tool.GetMetadata().Name, Version: tool.GetVersion()}
+		entry.RepairResult, err = api.Repair(context.Background(), tool, callback)
+		finishProgress()
+		if err != nil {
+			entry.Error = err.Error()
+			exitCode = 1
+		}
+		entries = append(entries, entry)
+
+		if *jsonOutput {
+			continue
+		}
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Failed to repair %s %s: %v\n", entry.Tool, entry.Version, err)
+		case entry.Reinstalled:
+			fmt.Printf("%s %s installed again\n", entry.Tool, entry.Version)
+		case len(entry.Problems) > 0:
+			fmt.Printf("%s %s: %d files repaired\n", entry.Tool, entry.Version, len(entry.Problems))
+		default:
+			fmt.Printf("%s %s: ok\n", entry.Tool, entry.Version)
+		}
+	}
+	if *jsonOutput {
+		printJSON(entries)
+	}
+	return exitCode
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+	"github.com/kira1928/remotetools/pkg/version"
+)
+
+// remotetools self-update [--check] [--public-key key | --public-key-file path] [--repo owner/name]
+func runSelfUpdate(args []string) int {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	check := fs.Bool("check", false, "only check whether a newer release exists")
+	publicKey := fs.String("public-key", "", "public key to verify the release with (default: the key built into the binary)")
+	publicKeyFile := fs.String("public-key-file", "", "read the public key from a file, e.g. an armored gpg key")
+	repo := fs.String("repo", tools.SelfUpdateRepo, "GitHub repository to update from")
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools self-update [--check] [--public-key key | --public-key-file path] [--repo owner/name]")
+		return 2
+	}
+
+	opts := tools.SelfUpdateOptions{
+		Repo:      *repo,
+		PublicKey: version.PublicKey,
+		CheckOnly: *check,
+	}
+	if *publicKey != "" {
+		opts.PublicKey = *publicKey
+	}
+	if *publicKeyFile != "" {
+		data, err := os.ReadFile(*publicKeyFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to read public key:", err)
+			return 1
+		}
+		opts.PublicKey = string(data)
+	}
+
+	result, err := tools.SelfUpdate(context.Background(), opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to update:", err)
+		return 1
+	}
+	switch {
+	case !result.Updated:
+		fmt.Printf("remotetools %s is up to date (latest release: %s)\n", result.CurrentVersion, result.LatestVersion)
+	case *check:
+		fmt.Printf("remotetools %s can be updated to %s\n", result.CurrentVersion, result.LatestVersion)
+	default:
+		fmt.Printf("remotetools updated from %s to %s\n", result.CurrentVersion, result.LatestVersion)
+	}
+	return 0
+}
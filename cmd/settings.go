@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/settings"
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+const defaultConfigPath = "config/sample.json"
+
+// commonFlags 为每个子命令注册共享的设置项，优先级见 settings 包
+type commonFlags struct {
+	settingsFile *string
+	values       map[string]*string
+	fs           *flag.FlagSet
+	offline      *bool
+	devOverrides stringList
+	devBuilds    stringList
+}
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func addCommonFlags(fs *flag.FlagSet) *commonFlags {
+	c := &commonFlags{
+		fs:           fs,
+		settingsFile: fs.String("settings", "", "path to the settings file"),
+		values: map[string]*string{
+			settings.KeyConfig:      fs.String("config", "", "path to the config file, later files in a path list override earlier ones (default \""+defaultConfigPath+"\")"),
+			settings.KeyRoot:        fs.String("root", "", "folder tools are installed to"),
+			settings.KeyInstallMode: fs.String("install-mode", "", "global, or project to install into .remotetools/ of the project (default \"global\")"),
+			settings.KeyProxy:       fs.String("proxy", "", "proxy for downloads, as http://, https:// or socks5:// URL"),
+			settings.KeyMaxSize:     fs.String("max-size", "", "size limit of the tool folder such as 10GB, least recently used versions are removed after installs"),
+		},
+	}
+	c.offline = fs.Bool("offline", false, "fail instead of accessing the network, only installed versions are used")
+	fs.Var(&c.devOverrides, "dev-override", "use a local binary for a tool, as tool=/path (repeatable)")
+	fs.Var(&c.devBuilds, "dev-build", "rebuild an overridden tool when its sources change, as tool=command (repeatable)")
+	return c
+}
+
+func (c *commonFlags) resolve() (*settings.Resolver, error) {
+	r := settings.Load()
+	if *c.settingsFile != "" {
+		if err := r.LoadFile(*c.settingsFile); err != nil {
+			return nil, err
+		}
+	}
+	r.SetDefault(settings.KeyConfig, defaultConfigPath)
+	for key, value := range c.values {
+		r.SetFlag(key, *value)
+	}
+	// -offline=false 也要覆盖环境变量和设置文件，因此只看是否给出了该参数
+	c.fs.Visit(func(f *flag.Flag) {
+		if f.Name == "offline" {
+			r.SetFlag(settings.KeyOffline, strconv.FormatBool(*c.offline))
+		}
+	})
+	return r, nil
+}
+
+// loadAPI applies the resolved settings and loads the config files.
+func (c *commonFlags) loadAPI() (*tools.API, error) {
+	r, err := c.resolve()
+	if err != nil {
+		return nil, err
+	}
+	tools.ApplySettings(r)
+	if err = tools.LoadDevToolOverrides(tools.DevOverridesFile); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, override := range c.devOverrides {
+		toolName, path, ok := strings.Cut(override, "=")
+		if !ok || toolName == "" || path == "" {
+			return nil, fmt.Errorf("invalid -dev-override %q, expected tool=/path", override)
+		}
+		tools.SetDevToolOverride(toolName, path)
+	}
+	for _, build := range c.devBuilds {
+		toolName, command, ok := strings.Cut(build, "=")
+		if !ok || toolName == "" || command == "" {
+			return nil, fmt.Errorf("invalid -dev-build %q, expected tool=command", build)
+		}
+		tools.SetDevToolBuild(toolName, command, "")
+	}
+	api := tools.Get()
+	if err = api.LoadConfigs(filepath.SplitList(r.Get(settings.KeyConfig))...); err != nil {
+		return nil, err
+	}
+	if err = api.LoadProjectPins("."); err != nil {
+		return nil, err
+	}
+	return api, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// remotetools shims <dir>
+func runShims(args []string) int {
+	fs := flag.NewFlagSet("shims", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools shims <dir>")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	shims, err := api.CreateShims(positional[0])
+	for _, shim := range shims {
+		fmt.Println(shim)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to create shims:", err)
+		return 1
+	}
+	return 0
+}
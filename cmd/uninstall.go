@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// remotetools uninstall <tool[@version]>
+// 仍被其他已安装工具依赖的版本不会被删除
+func runUninstall(args []string) int {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools uninstall <tool[@version]>")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	toolName, version := splitToolVersion(positional[0])
+	if err = api.Uninstall(toolName, version); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to uninstall %s: %v\n", positional[0], err)
+		return 1
+	}
+	fmt.Printf("%s uninstalled\n", positional[0])
+	return 0
+}
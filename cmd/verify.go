@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+type verifyEntry struct {
+	Tool     string              `json:"tool"`
+	Version  string              `json:"version"`
+	OK       bool                `json:"ok"`
+	Problems []tools.FileProblem `json:"problems,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// remotetools verify [tool[@version]...] [--json]
+// 不指定工具时检查所有已安装的版本，有文件缺失或损坏时退出码为 1
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+	positional, _ := parseArgs(fs, args)
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	var targets []tools.Tool
+	if len(positional) == 0 {
+		for _, toolName := range api.ToolNames() {
+			versions, _ := api.GetInstalledVersions(toolName)
+			for _, version := range versions {
+				if tool, err := api.GetToolWithVersion(toolName, version); err == nil {
+					targets = append(targets, tool)
+				}
+			}
+		}
+	}
+	for _, arg := range positional {
+		tool, err := getTool(api, arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		targets = append(targets, tool)
+	}
+
+	exitCode := 0
+	entries := []verifyEntry{}
+	for _, tool := range targets {
+		entry := verifyEntry{Tool: tool.GetMetadata().Name, Version: tool.GetVersion()}
+		problems, err := api.Verify(tool)
+		switch {
+		case errors.Is(err, tools.ErrNoManifest):
+			entry.OK = true
+			entry.Error = err.Error()
+		case err != nil:
+			entry.Error = err.Error()
+			exitCode = 1
+		default:
+			entry.OK = len(problems) == 0
+			entry.Problems = problems
+			if !entry.OK {
+				exitCode = 1
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	if *jsonOutput {
+		printJSON(entries)
+		return exitCode
+	}
+	for _, entry := range entries {
+		switch {
+		case entry.Error != "" && entry.OK:
+			fmt.Printf("%s %s: %s, skipped\n", entry.Tool, entry.Version, entry.Error)
+		case entry.Error != "":
+			fmt.Printf("%s %s: %s\n", entry.Tool, entry.Version, entry.Error)
+		case entry.OK:
+			fmt.Printf("%s %s: ok\n", entry.Tool, entry.Version)
+		default:
+			fmt.Printf("%s %s: %d files do not match the manifest\n", entry.Tool, entry.Version, len(entry.Problems))
+			for _, problem := range entry.Problems {
+				fmt.Printf("    %-8s %s\n", problem.Problem, problem.Path)
+			}
+		}
+	}
+	return exitCode
+}
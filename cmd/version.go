@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/kira1928/remotetools/pkg/version"
+)
+
+// remotetools version [--json]
+func runVersion(args []string) int {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+	parseArgs(fs, args)
+
+	info := version.Get()
+	module, hasModule := version.GetModuleInfo()
+	if *jsonOutput {
+		output := struct {
+			version.BuildInfo
+			Module *version.ModuleInfo `json:"module,omitempty"`
+		}{BuildInfo: info}
+		if hasModule {
+			output.Module = &module
+		}
+		printJSON(output)
+		return 0
+	}
+
+	fmt.Printf("remotetools %s\n", info.Version)
+	if info.Commit != "" {
+		fmt.Printf("commit:     %s\n", info.Commit)
+	}
+	if info.BuildTime != "" {
+		fmt.Printf("built:      %s\n", info.BuildTime)
+	}
+	if hasModule {
+		revision := module.Revision
+		if module.Dirty {
+			revision += " (dirty)"
+		}
+		fmt.Printf("module:     %s %s %s\n", module.Path, module.Version, revision)
+	}
+	fmt.Printf("go:         %s %s\n", info.GoVersion, info.Platform)
+	return 0
+}
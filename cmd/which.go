@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// remotetools which <tool>[@version]
+// 打印实际会被执行的文件路径（已考虑 dev override），工具不存在时返回 1
+func runWhich(args []string) int {
+	fs := flag.NewFlagSet("which", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	positional, _ := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: remotetools which <tool>[@version]")
+		return 2
+	}
+
+	api, err := common.loadAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return 1
+	}
+	tool, err := getTool(api, positional[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !tool.DoesToolExist() {
+		fmt.Fprintf(os.Stderr, "%s is not installed\n", positional[0])
+		return 1
+	}
+
+	path := tool.GetToolPath()
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	fmt.Println(path)
+	return 0
+}
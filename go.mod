@@ -1,3 +1,18 @@
 module github.com/kira1928/remotetools
 
 go 1.19
+
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/Masterminds/semver/v3 v3.2.1
+	github.com/ProtonMail/go-crypto v1.0.0
+	github.com/klauspost/compress v1.17.6
+	golang.org/x/crypto v0.14.0
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/cloudflare/circl v1.3.3 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+)
@@ -0,0 +1,32 @@
+package config
+
+const (
+	ChannelStable  = "stable"
+	ChannelBeta    = "beta"
+	ChannelNightly = "nightly"
+)
+
+var channelLevels = map[string]int{
+	ChannelStable:  0,
+	ChannelBeta:    1,
+	ChannelNightly: 2,
+}
+
+// ChannelIncludes reports whether versions released on toolChannel are
+// available to someone following channel. Each of the known channels includes
+// the more stable ones, e.g. beta includes stable. Other channels only include
+// themselves. An empty channel is stable.
+func ChannelIncludes(channel, toolChannel string) bool {
+	if channel == "" {
+		channel = ChannelStable
+	}
+	if toolChannel == "" {
+		toolChannel = ChannelStable
+	}
+	level, known := channelLevels[channel]
+	toolLevel, toolKnown := channelLevels[toolChannel]
+	if !known || !toolKnown {
+		return channel == toolChannel
+	}
+	return toolLevel <= level
+}
@@ -5,97 +5,355 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 )
 
 type ToolConfig struct {
 	ToolName    string
 	Version     string               `json:"version"`
 	DownloadURL OsArchSpecificString `json:"downloadUrl"`
-	PathToEntry OsArchSpecificString `json:"pathToEntry"`
+	// .sha256 or SHASUMS file published next to the artifact
+	ChecksumURL OsArchSpecificString `json:"checksumUrl"`
+	// expected sha256 of the artifact, takes precedence over checksumUrl
+	SHA256 OsArchSpecificString `json:"sha256"`
+	// detached minisign or GPG signature of the artifact, checked with PublicKey
+	SignatureURL OsArchSpecificString `json:"signatureUrl"`
+	// minisign public key, or an armored GPG key ring
+	PublicKey string `json:"publicKey"`
+	// minisign or gpg, detected from PublicKey when empty
+	SignatureType string               `json:"signatureType"`
+	PathToEntry   OsArchSpecificString `json:"pathToEntry"`
+	Scope         string               `json:"scope"`
+	// args that make the tool print its version/environment info, e.g. ["--info"]
+	PrintInfoCmd []string `json:"printInfoCmd"`
+	// host env vars kept when the tool runs with an isolated environment
+	EnvWhitelist []string `json:"envWhitelist"`
+	// variables set for the tool process, e.g. {"JAVA_HOME": "{toolFolder}"}.
+	// {toolFolder} is the install folder, also in postInstall/preUninstall
+	Env map[string]string `json:"env"`
+	// working directory of the tool process, relative paths are resolved
+	// against the tool folder, so "." starts the tool from its own folder
+	WorkingDir string `json:"workingDir"`
+	// false for tools that only ship data/libraries, defaults to true
+	Executable *bool `json:"isExecutable"`
+	// extra request headers for downloads, e.g. {"Authorization": "Bearer ${TOKEN}"};
+	// ${NAME} is replaced with the environment variable NAME
+	Headers map[string]string `json:"headers"`
+	// download bandwidth limit in bytes per second, 0 for no limit
+	DownloadRateLimit int64 `json:"downloadRateLimit"`
+	// encoding of zip entry names without the UTF-8 flag: auto (default), cp437, gbk or shift_jis
+	ZipFilenameEncoding string `json:"zipFilenameEncoding"`
+	// number of leading path components removed from archive entries
+	StripPrefix int `json:"stripPrefix"`
+	// only this folder of the archive is extracted, applied after stripPrefix
+	ExtractSubdir string `json:"extractSubdir"`
+	// release channel of this version: stable (default), beta or nightly
+	Channel string `json:"channel"`
+	// where the artifact comes from: url (default, see downloadUrl), github or git
+	Source string `json:"source"`
+	// owner/repo of a github source, or the clone URL of a git source
+	Repo string `json:"repo"`
+	// release asset name of a github source, or the layer title of an oci://
+	// artifact with several layers, may use placeholders
+	Asset OsArchSpecificString `json:"asset"`
+	// release tag of a github source or tag of a git source, "v{version}" by
+	// default. Version "latest" uses the latest github release instead.
+	Tag string `json:"tag"`
+	// command run through the shell in the cloned folder of a git source
+	BuildCommand string `json:"build"`
+	// commands run through the shell in the tool folder after it is installed,
+	// e.g. "./bin/tool --help" to warm it up. The install is rolled back when
+	// one of them fails
+	PostInstall []string `json:"postInstall"`
+	// commands run through the shell in the tool folder before it is uninstalled,
+	// the uninstall is aborted when one of them fails
+	PreUninstall []string `json:"preUninstall"`
+	// tools installed before this one by API.Install, as "tool", "tool@version"
+	// or "tool@<constraint>" such as "dotnet@8.x"
+	Dependencies []string `json:"dependencies"`
+	// token for the source API, ${GITHUB_TOKEN} by default for github. oci://
+	// artifacts use the docker login credentials when it is not set
+	Token string `json:"token"`
+
+	// platform the values were resolved for by ForPlatform, the current
+	// platform when empty
+	goos, goarch string
+}
+
+func (p *ToolConfig) IsExecutable() bool {
+	return p.Executable == nil || *p.Executable
+}
+
+func (p *ToolConfig) GetChannel() string {
+	if p.Channel == "" {
+		return ChannelStable
+	}
+	return p.Channel
 }
 
 type OsArchSpecificString struct {
 	Value string
+	// Values holds every value for the current platform when it is given as an
+	// array, e.g. mirrors of a download URL. Value is the first of them.
+	Values []string
+	// raw keeps the original JSON so values for other platforms can be resolved
+	raw json.RawMessage
 }
 
 type Config struct {
+	// default version of each tool
 	ToolConfigs map[string]*ToolConfig `json:"tools"`
+	// every configured version of each tool, including the default one
+	VersionConfigs map[string]map[string]*ToolConfig `json:"-"`
+	// 用不带版本的键配置了默认版本的工具，其余工具默认使用最高版本
+	explicitDefaults map[string]bool
 }
 
 func (p *OsArchSpecificString) UnmarshalJSON(data []byte) (err error) {
-	// Try to unmarshal the data into a string
-	var url string
-	err = json.Unmarshal(data, &url)
-	if err == nil {
-		/*
-			"https://xxx"
-		*/
-		p.Value = url
-		return
+	p.raw = append(json.RawMessage(nil), data...)
+	p.Values, err = p.ValuesFor(runtime.GOOS, runtime.GOARCH)
+	p.Value = ""
+	if len(p.Values) > 0 {
+		p.Value = p.Values[0]
+	}
+	return
+}
+
+func (p OsArchSpecificString) MarshalJSON() ([]byte, error) {
+	if p.raw != nil {
+		return p.raw, nil
+	}
+	return json.Marshal(p.Value)
+}
+
+// ValueFor resolves the value for the given platform. Values set without JSON
+// are the same for every platform.
+func (p *OsArchSpecificString) ValueFor(goos, goarch string) (result string, err error) {
+	values, err := p.ValuesFor(goos, goarch)
+	if len(values) > 0 {
+		result = values[0]
+	}
+	return
+}
+
+// ValuesFor resolves all values for the given platform. Any level may be an
+// array of strings instead of a single string:
+//
+//	"https://xxx"
+//	["https://xxx", "https://mirror/xxx"]
+//	{"linux": "https://xxx", "windows": ["https://xxx", "https://mirror/xxx"]}
+//	{"windows": {"amd64": ["https://xxx", "https://mirror/xxx"], "arm64": "https://xxx"}}
+func (p *OsArchSpecificString) ValuesFor(goos, goarch string) (result []string, err error) {
+	if p.raw == nil {
+		if len(p.Values) > 0 {
+			return p.Values, nil
+		}
+		if p.Value == "" {
+			return nil, nil
+		}
+		return []string{p.Value}, nil
+	}
+	data := p.raw
+
+	// a string or an array of strings for every platform
+	if result, ok := parseStringOrArray(data); ok {
+		return result, nil
 	}
 
 	// Try to unmarshal the data into a map
-	var urlMap map[string]interface{}
-	err = json.Unmarshal(data, &urlMap)
-	if err == nil {
-		value, ok := urlMap[runtime.GOOS]
-		if !ok || value == nil {
-			return fmt.Errorf("no value for %s in %v", runtime.GOOS, data)
-		} else if url, ok := value.(string); ok {
-			/*
-				{
-					"darwin": "https://xxx",
-					"linux": "https://xxx",
-					"windows": "https://xxx"
-				}
-			*/
-			p.Value = url
-			return
-		} else if urlMapForArch, ok := value.(map[string]interface{}); ok {
-			value, ok := urlMapForArch[runtime.GOARCH]
-			if !ok || value == nil {
-				return fmt.Errorf("no value for %s/%s in %v", runtime.GOOS, runtime.GOARCH, data)
-			} else if url, ok := value.(string); ok {
-				/*
-					{
-						"darwin": ...,
-						"linux": ...,
-						"windows": {
-							"386": "https://xxx",
-							"amd64": "https://xxx"
-							"arm64": "https://xxx
-							"arm": "https://xxx"
-						}
-					}
-				*/
-				p.Value = url
-				return
-			} else {
-				return fmt.Errorf("value for %s/%s is not a string: %v", runtime.GOOS, runtime.GOARCH, value)
-			}
-		} else {
-			return fmt.Errorf("value for %s is not a string or a map: %v", runtime.GOOS, value)
+	var osMap map[string]json.RawMessage
+	if err = json.Unmarshal(data, &osMap); err != nil {
+		return nil, nil
+	}
+	value, ok := osMap[goos]
+	if !ok || string(value) == "null" {
+		return nil, fmt.Errorf("no value for %s in %s", goos, data)
+	}
+	if result, ok := parseStringOrArray(value); ok {
+		return result, nil
+	}
+
+	var archMap map[string]json.RawMessage
+	if err = json.Unmarshal(value, &archMap); err != nil {
+		return nil, fmt.Errorf("value for %s is not a string, an array or a map: %s", goos, value)
+	}
+	value, ok = archMap[goarch]
+	if !ok || string(value) == "null" {
+		return nil, fmt.Errorf("no value for %s/%s in %s", goos, goarch, data)
+	}
+	if result, ok := parseStringOrArray(value); ok {
+		return result, nil
+	}
+	return nil, fmt.Errorf("value for %s/%s is not a string or an array: %s", goos, goarch, value)
+}
+
+// forPlatform returns a copy with Value and Values resolved for the platform.
+func (p OsArchSpecificString) forPlatform(goos, goarch string) (result OsArchSpecificString, err error) {
+	result = p
+	if result.Values, err = p.ValuesFor(goos, goarch); err != nil {
+		return
+	}
+	result.Value = ""
+	if len(result.Values) > 0 {
+		result.Value = result.Values[0]
+	}
+	return
+}
+
+// ForPlatform returns a copy of the tool config whose platform specific values
+// and placeholders are resolved for goos/goarch instead of the current
+// platform, e.g. to download the artifacts of other platforms.
+func (p *ToolConfig) ForPlatform(goos, goarch string) (*ToolConfig, error) {
+	result := *p
+	result.goos, result.goarch = goos, goarch
+	for _, field := range []*OsArchSpecificString{
+		&result.DownloadURL, &result.ChecksumURL, &result.SHA256,
+		&result.SignatureURL, &result.PathToEntry, &result.Asset,
+	} {
+		resolved, err := field.forPlatform(goos, goarch)
+		if err != nil {
+			return nil, err
 		}
+		*field = resolved
 	}
+	return &result, nil
+}
 
-	return nil
+func parseStringOrArray(data json.RawMessage) ([]string, bool) {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		return []string{value}, true
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err == nil {
+		return values, true
+	}
+	return nil, false
 }
 
+// LoadConfig reads a JSON, YAML or TOML config file, chosen by its extension.
 func LoadConfig(path string) (conf Config, err error) {
-	// Read the JSON file
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return
 	}
+	return ParseConfig(data, FormatOf(path))
+}
+
+// LoadConfigs loads the config files in order and merges them, see Merge.
+func LoadConfigs(paths ...string) (conf Config, err error) {
+	conf = newConfig()
+	for _, path := range paths {
+		layer, err := LoadConfig(path)
+		if err != nil {
+			return conf, fmt.Errorf("failed to load config %s: %w", path, err)
+		}
+		conf.Merge(layer)
+	}
+	return
+}
+
+// ParseConfig parses config data in the given format (FormatJSON, FormatYAML or FormatTOML).
+// A tool is configured by its name, or by name@version to configure more versions
+// of it. The entry without a version is the default version of the tool, or the
+// highest stable version when there is none.
+func ParseConfig(data []byte, format string) (conf Config, err error) {
+	data, err = toJSON(format, data)
+	if err != nil {
+		return
+	}
 
 	// Unmarshal the JSON data into the config struct
-	err = json.Unmarshal(data, &conf.ToolConfigs)
+	var entries map[string]*ToolConfig
+	err = json.Unmarshal(data, &entries)
 	if err != nil {
 		return
 	}
-	for toolName, toolConfig := range conf.ToolConfigs {
+
+	conf = newConfig()
+	for key, toolConfig := range entries {
+		if toolConfig == nil {
+			continue
+		}
+		toolName, version, versioned := strings.Cut(key, "@")
+		if versioned {
+			if toolConfig.Version == "" {
+				toolConfig.Version = version
+			} else if toolConfig.Version != version {
+				return conf, fmt.Errorf("version of %s does not match its key: %s", key, toolConfig.Version)
+			}
+		} else {
+			conf.ToolConfigs[toolName] = toolConfig
+			conf.explicitDefaults[toolName] = true
+		}
 		toolConfig.ToolName = toolName
+		conf.addVersion(toolConfig)
+	}
+	conf.resolveDefaults()
+
+	return
+}
+
+func newConfig() Config {
+	return Config{
+		ToolConfigs:      make(map[string]*ToolConfig),
+		VersionConfigs:   make(map[string]map[string]*ToolConfig),
+		explicitDefaults: make(map[string]bool),
+	}
+}
+
+func (p *Config) addVersion(toolConfig *ToolConfig) {
+	versions := p.VersionConfigs[toolConfig.ToolName]
+	if versions == nil {
+		versions = make(map[string]*ToolConfig)
+		p.VersionConfigs[toolConfig.ToolName] = versions
+	}
+	versions[toolConfig.Version] = toolConfig
+}
+
+func (p *Config) resolveDefaults() {
+	for toolName, versions := range p.VersionConfigs {
+		if p.explicitDefaults[toolName] {
+			continue
+		}
+		// 预览版只在没有稳定版时作为默认版本
+		var latest, latestStable *ToolConfig
+		for _, toolConfig := range versions {
+			if latest == nil || CompareVersions(toolConfig.Version, latest.Version) > 0 {
+				latest = toolConfig
+			}
+			if toolConfig.GetChannel() == ChannelStable &&
+				(latestStable == nil || CompareVersions(toolConfig.Version, latestStable.Version) > 0) {
+				latestStable = toolConfig
+			}
+		}
+		if latestStable != nil {
+			latest = latestStable
+		}
+		p.ToolConfigs[toolName] = latest
 	}
+}
 
+// GetVersionConfig returns the config of a configured version of a tool.
+func (p *Config) GetVersionConfig(toolName, version string) (toolConfig *ToolConfig, ok bool) {
+	toolConfig, ok = p.VersionConfigs[toolName][version]
 	return
 }
+
+// Merge 将 other 中的工具配置合并进来，同一工具的同一版本整体覆盖已有的配置，
+// other 中不带版本的配置会成为新的默认版本
+func (p *Config) Merge(other Config) {
+	if p.VersionConfigs == nil {
+		*p = newConfig()
+	}
+	for _, versions := range other.VersionConfigs {
+		for _, toolConfig := range versions {
+			p.addVersion(toolConfig)
+		}
+	}
+	for toolName := range other.explicitDefaults {
+		p.ToolConfigs[toolName] = other.ToolConfigs[toolName]
+		p.explicitDefaults[toolName] = true
+	}
+	p.resolveDefaults()
+}
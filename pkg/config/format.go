@@ -0,0 +1,118 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// FormatOf 根据扩展名判断配置文件格式，未知扩展名按 JSON 处理
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// toJSON converts YAML and TOML documents to JSON, so every format goes through
+// the same json.Unmarshal and the custom UnmarshalJSON methods.
+func toJSON(format string, data []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	switch format {
+	case FormatJSON:
+		return data, nil
+	case FormatYAML:
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, fmt.Errorf("invalid yaml: %w", err)
+		}
+		quoteYAMLStringFields(&node)
+		if err := node.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("invalid yaml: %w", err)
+		}
+	case FormatTOML:
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid toml: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format)
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	if err := checkStringFields(doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// stringFields 是 ToolConfig 中字符串类型的配置项，如 version
+var stringFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(ToolConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" && t.Field(i).Type.Kind() == reflect.String {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// quoteYAMLStringFields 让工具配置中字符串类型的项保留原文，
+// 否则 version: 1.10 会被解析为数字 1.1
+func quoteYAMLStringFields(node *yaml.Node) {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 1; i < len(node.Content); i += 2 {
+		toolNode := node.Content[i]
+		if toolNode.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(toolNode.Content); j += 2 {
+			value := toolNode.Content[j+1]
+			if stringFields[toolNode.Content[j].Value] && value.Kind == yaml.ScalarNode && value.Tag != "!!null" {
+				value.Tag = "!!str"
+			}
+		}
+	}
+}
+
+// checkStringFields 拒绝字符串类型的项写成数字或布尔值，TOML 中的 version = 8.0
+// 已经丢失了原文，只能要求加上引号
+func checkStringFields(doc map[string]interface{}) error {
+	for toolName, tool := range doc {
+		fields, ok := tool.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for name, value := range fields {
+			if !stringFields[name] || value == nil {
+				continue
+			}
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("%s of %s must be a string, quote the value %v so it is kept as written", name, toolName, value)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandEnv replaces ${NAME} in s with the environment variable NAME. It fails
+// when a referenced variable is not set, rather than sending a request with an
+// empty credential.
+func ExpandEnv(s string) (expanded string, err error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	expanded = envPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envPattern.FindStringSubmatch(ref)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return
+}
+
+// Expand expands the placeholders in s for the platform of the config, see
+// ExpandFor and ForPlatform.
+func (p *ToolConfig) Expand(s string) string {
+	if p.goos != "" {
+		return p.ExpandFor(s, p.goos, p.goarch)
+	}
+	return p.ExpandFor(s, runtime.GOOS, runtime.GOARCH)
+}
+
+// ExpandFor 展开 {version}、{os}、{arch} 占位符。${ENV_VAR} 保持原样，由 ExpandEnv
+// 在使用前展开，这样环境变量中的凭据不会出现在安装记录和错误信息中
+func (p *ToolConfig) ExpandFor(s, goos, goarch string) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	return strings.NewReplacer(
+		"{version}", p.Version,
+		"{os}", goos,
+		"{arch}", goarch,
+	).Replace(s)
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// CompareVersions compares two versions as semver, returning -1, 0 or 1.
+// Versions that are not semver are compared as strings and sort below semver ones.
+func CompareVersions(a, b string) int {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	switch {
+	case errA == nil && errB == nil:
+		return va.Compare(vb)
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// MatchVersions returns the versions satisfying the constraint, e.g. ">=8.0.4 <9.0.0",
+// highest first. Versions that are not semver never match.
+func MatchVersions(versions []string, constraint string) (matched []string, err error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+	for _, version := range versions {
+		if v, err := semver.NewVersion(version); err == nil && c.Check(v) {
+			matched = append(matched, version)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return CompareVersions(matched[i], matched[j]) > 0
+	})
+	return
+}
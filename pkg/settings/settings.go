@@ -0,0 +1,168 @@
+// Package settings resolves remotetools options shared by the library and the CLI.
+//
+// Every option can come from several places. The first one that provides a
+// non-empty value wins:
+//
+//  1. command line flags (SetFlag)
+//  2. environment variables (REMOTETOOLS_*)
+//  3. the settings file (LoadFile, JSON object of key to value)
+//  4. defaults (SetDefault)
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	KeyRoot       = "root"
+	KeyConfig     = "config"
+	KeyCache      = "cache"
+	KeyTmp        = "tmp"
+	KeyUserRoot   = "userRoot"
+	KeySystemRoot = "systemRoot"
+	// "global" (default) or "project", which installs into .remotetools/ of the project
+	KeyInstallMode = "installMode"
+	// proxy for downloads, HTTP_PROXY/HTTPS_PROXY/NO_PROXY are used when empty
+	KeyProxy = "proxy"
+	// "true" to refuse network access and only use installed versions
+	KeyOffline = "offline"
+	// size limit of the tool folder such as "10GB", see tools.SetMaxToolFolderSize
+	KeyMaxSize = "maxSize"
+	// "true" to hard link identical files of installed tools, see tools.SetDedupe
+	KeyDedupe = "dedupe"
+	// "true" to compare installed files with their manifest, see tools.SetStrictInstallCheck
+	KeyStrictCheck = "strictCheck"
+)
+
+// EnvSettingsFile overrides the location of the settings file.
+const EnvSettingsFile = "REMOTETOOLS_SETTINGS"
+
+var envNames = map[string]string{
+	KeyRoot:        "REMOTETOOLS_ROOT",
+	KeyConfig:      "REMOTETOOLS_CONFIG",
+	KeyCache:       "REMOTETOOLS_CACHE",
+	KeyTmp:         "REMOTETOOLS_TMP",
+	KeyUserRoot:    "REMOTETOOLS_USER_ROOT",
+	KeySystemRoot:  "REMOTETOOLS_SYSTEM_ROOT",
+	KeyInstallMode: "REMOTETOOLS_INSTALL_MODE",
+	KeyProxy:       "REMOTETOOLS_PROXY",
+	KeyOffline:     "REMOTETOOLS_OFFLINE",
+	KeyMaxSize:     "REMOTETOOLS_MAX_SIZE",
+	KeyDedupe:      "REMOTETOOLS_DEDUPE",
+	KeyStrictCheck: "REMOTETOOLS_STRICT_CHECK",
+}
+
+type Source int
+
+const (
+	SourceNone Source = iota
+	SourceDefault
+	SourceFile
+	SourceEnv
+	SourceFlag
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceFile:
+		return "file"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	default:
+		return "none"
+	}
+}
+
+type Resolver struct {
+	flags    map[string]string
+	file     map[string]string
+	defaults map[string]string
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{
+		flags:    make(map[string]string),
+		file:     make(map[string]string),
+		defaults: make(map[string]string),
+	}
+}
+
+// Load creates a resolver with the default settings file loaded, if it exists.
+func Load() *Resolver {
+	r := NewResolver()
+	if path := DefaultFilePath(); path != "" {
+		if err := r.LoadFile(path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "failed to load settings file %s: %v\n", path, err)
+		}
+	}
+	return r
+}
+
+// DefaultFilePath returns $REMOTETOOLS_SETTINGS, or settings.json in the user config folder.
+func DefaultFilePath() string {
+	if path := os.Getenv(EnvSettingsFile); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "remotetools", "settings.json")
+}
+
+func (r *Resolver) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	file := make(map[string]string)
+	if err = json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	r.file = file
+	return nil
+}
+
+func (r *Resolver) SetFlag(key, value string) {
+	r.flags[key] = value
+}
+
+func (r *Resolver) SetDefault(key, value string) {
+	r.defaults[key] = value
+}
+
+func (r *Resolver) Get(key string) string {
+	value, _ := r.Lookup(key)
+	return value
+}
+
+// Lookup returns the effective value of an option and where it came from.
+func (r *Resolver) Lookup(key string) (string, Source) {
+	if value := r.flags[key]; value != "" {
+		return value, SourceFlag
+	}
+	if envName, ok := envNames[key]; ok {
+		if value := os.Getenv(envName); value != "" {
+			return value, SourceEnv
+		}
+	}
+	if value := r.file[key]; value != "" {
+		return value, SourceFile
+	}
+	if value := r.defaults[key]; value != "" {
+		return value, SourceDefault
+	}
+	return "", SourceNone
+}
+
+// EnvName returns the environment variable that sets an option.
+func EnvName(key string) string {
+	return envNames[key]
+}
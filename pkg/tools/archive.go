@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	ArchiveZip    = "zip"
+	ArchiveTarGz  = "tar.gz"
+	ArchiveTarBz2 = "tar.bz2"
+	ArchiveTarZst = "tar.zst"
+)
+
+// archiveFormat 根据文件名判断归档格式，不支持时返回空字符串
+func archiveFormat(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGz
+	case strings.HasSuffix(name, ".tar.bz2"), strings.HasSuffix(name, ".tbz2"), strings.HasSuffix(name, ".tbz"):
+		return ArchiveTarBz2
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return ArchiveTarZst
+	default:
+		return ""
+	}
+}
+
+// openTarFile opens a compressed tar archive. The returned function closes the
+// file and the decompressor.
+func openTarFile(path, format string) (tarReader *tar.Reader, closeArchive func(), err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+
+	var r io.Reader
+	closeArchive = func() { file.Close() }
+	switch format {
+	case ArchiveTarGz:
+		gzReader, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		r = gzReader
+		closeArchive = func() {
+			gzReader.Close()
+			file.Close()
+		}
+	case ArchiveTarBz2:
+		r = bzip2.NewReader(file)
+	case ArchiveTarZst:
+		zstReader, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		r = zstReader
+		closeArchive = func() {
+			zstReader.Close()
+			file.Close()
+		}
+	default:
+		file.Close()
+		return nil, nil, fmt.Errorf("unsupported file format: %s", path)
+	}
+	return tar.NewReader(r), closeArchive, nil
+}
+
+type ArchiveEntry struct {
+	Name     string
+	Size     int64
+	Mode     os.FileMode
+	ModTime  time.Time
+	IsDir    bool
+	LinkName string
+}
+
+// InspectArchive lists the entries of a supported archive without extracting it.
+func InspectArchive(path string) (entries []ArchiveEntry, err error) {
+	switch format := archiveFormat(path); format {
+	case ArchiveZip:
+		return inspectZipFile(path)
+	case ArchiveTarGz, ArchiveTarBz2, ArchiveTarZst:
+		return inspectTarFile(path, format)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", path)
+	}
+}
+
+func inspectZipFile(path string) (entries []ArchiveEntry, err error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	names, err := decodeZipNames(r.File, ZipEncodingAuto)
+	if err != nil {
+		return
+	}
+	for i, f := range r.File {
+		info := f.FileInfo()
+		entries = append(entries, ArchiveEntry{
+			Name:    names[i],
+			Size:    int64(f.UncompressedSize64),
+			Mode:    info.Mode(),
+			ModTime: f.Modified,
+			IsDir:   info.IsDir(),
+		})
+	}
+	return
+}
+
+func inspectTarFile(path, format string) (entries []ArchiveEntry, err error) {
+	tarReader, closeArchive, err := openTarFile(path, format)
+	if err != nil {
+		return
+	}
+	defer closeArchive()
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		info := header.FileInfo()
+		entries = append(entries, ArchiveEntry{
+			Name:     header.Name,
+			Size:     header.Size,
+			Mode:     info.Mode(),
+			ModTime:  header.ModTime,
+			IsDir:    info.IsDir(),
+			LinkName: header.Linkname,
+		})
+	}
+	return
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveEntryPath(t *testing.T) {
+	dest := t.TempDir()
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "../x", wantErr: true},
+		{name: "a/../../x", wantErr: true},
+		{name: "/etc/x", wantErr: true},
+		{name: `C:\x`, wantErr: true},
+		{name: `..\x`, wantErr: true},
+		{name: "bin/sub/tool", want: filepath.Join(dest, "bin", "sub", "tool")},
+	}
+	for _, tt := range tests {
+		got, err := archiveEntryPath(dest, tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("archiveEntryPath(%q) = %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("archiveEntryPath(%q) returned %v", tt.name, err)
+		} else if got != tt.want {
+			t.Errorf("archiveEntryPath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExtractZipSlip(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("../evil.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("evil"))
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	testExtractSlip(t, "evil.zip", buf.Bytes())
+}
+
+func TestExtractTarGzSlip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	content := []byte("evil")
+	if err := w.WriteHeader(&tar.Header{Name: "../evil.txt", Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(content)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	testExtractSlip(t, "evil.tar.gz", buf.Bytes())
+}
+
+// testExtractSlip 解压包含 ../evil.txt 的归档，解压应失败且 dest 外没有写入文件
+func testExtractSlip(t *testing.T, fileName string, data []byte) {
+	root := t.TempDir()
+	archivePath := filepath.Join(root, fileName)
+	if err := os.WriteFile(archivePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(root, "dest")
+	if err := os.Mkdir(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := extractArchive(archivePath, dest, extractOptions{}); err == nil {
+		t.Fatalf("extracting %s succeeded, want an error", fileName)
+	}
+	if _, err := os.Stat(filepath.Join(root, "evil.txt")); !os.IsNotExist(err) {
+		t.Fatalf("evil.txt was written outside of dest: %v", err)
+	}
+}
+
+func TestExtractTarGzWithoutDirEntries(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	content := []byte("nested")
+	if err := w.WriteHeader(&tar.Header{Name: "a/b/c.txt", Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(content)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	archivePath := filepath.Join(root, "nested.tar.gz")
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(root, "dest")
+	if err := extractArchive(archivePath, dest, extractOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "a", "b", "c.txt"))
+	if err != nil || string(data) != "nested" {
+		t.Fatalf("a/b/c.txt = %q, %v", data, err)
+	}
+}
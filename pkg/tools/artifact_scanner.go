@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ArtifactScanner 在解压前检查下载的文件（如杀毒扫描），返回错误会中止安装
+type ArtifactScanner func(path string) error
+
+var (
+	artifactScannerLock sync.RWMutex
+	artifactScanner     ArtifactScanner
+)
+
+// SetArtifactScanner sets the hook run on every downloaded artifact before it
+// is extracted. Pass nil to remove it.
+func SetArtifactScanner(scanner ArtifactScanner) {
+	artifactScannerLock.Lock()
+	defer artifactScannerLock.Unlock()
+	artifactScanner = scanner
+}
+
+func scanArtifact(toolName, path string) error {
+	artifactScannerLock.RLock()
+	scanner := artifactScanner
+	artifactScannerLock.RUnlock()
+
+	if scanner == nil {
+		return nil
+	}
+	if err := scanner(path); err != nil {
+		return fmt.Errorf("artifact of tool %s rejected by scanner: %w", toolName, err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// AutoUpdateStrategy 控制 StartAutoUpdate 如何更新已安装的工具
+type AutoUpdateStrategy struct {
+	// Channel new versions are taken from, see GetToolLatest. Empty is stable.
+	Channel string
+	// RemoveOldVersions removes the older installed versions once the
+	// OnToolUpdated handlers returned.
+	RemoveOldVersions bool
+}
+
+// ToolUpdatedEvent 表示自动更新安装了某个工具的新版本
+type ToolUpdatedEvent struct {
+	ToolName string
+	// highest version installed before the update
+	OldVersion string
+	NewVersion string
+	Tool       Tool
+}
+
+type ToolUpdatedHandler func(event ToolUpdatedEvent)
+
+type autoUpdater struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// OnToolUpdated registers a handler called after auto update installed a new
+// version, so the host program can switch to it.
+func (p *API) OnToolUpdated(handler ToolUpdatedHandler) {
+	p.updateLock.Lock()
+	defer p.updateLock.Unlock()
+	p.updatedHandlers = append(p.updatedHandlers, handler)
+}
+
+// StartAutoUpdate runs AutoUpdate every interval until StopAutoUpdate is called.
+func (p *API) StartAutoUpdate(interval time.Duration, strategy AutoUpdateStrategy) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid auto update interval: %s", interval)
+	}
+
+	p.updateLock.Lock()
+	defer p.updateLock.Unlock()
+	if p.autoUpdater != nil {
+		return fmt.Errorf("auto update is already running")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updater := &autoUpdater{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	p.autoUpdater = updater
+
+	go func() {
+		defer close(updater.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := p.AutoUpdate(ctx, strategy); err != nil && ctx.Err() == nil {
+					log.Println("auto update failed:", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// StopAutoUpdate stops auto update and aborts a running download.
+func (p *API) StopAutoUpdate() {
+	p.updateLock.Lock()
+	updater := p.autoUpdater
+	p.autoUpdater = nil
+	p.updateLock.Unlock()
+
+	if updater == nil {
+		return
+	}
+	updater.cancel()
+	<-updater.done
+}
+
+// AutoUpdate reloads the config files and installs the latest version of every
+// tool that has an older version installed. Tools locked by a lockfile or pinned
+// by the project are left alone. Failing tools are logged and skipped.
+func (p *API) AutoUpdate(ctx context.Context, strategy AutoUpdateStrategy) (events []ToolUpdatedEvent, err error) {
+	if err = p.reloadConfigFiles(); err != nil {
+		return
+	}
+
+	for _, toolName := range p.ToolNames() {
+		if ctx.Err() != nil {
+			return events, ctx.Err()
+		}
+		event, updated, err := p.autoUpdateTool(ctx, toolName, strategy)
+		if err != nil {
+			log.Printf("failed to auto update %s: %v\n", toolName, err)
+			continue
+		}
+		if updated {
+			events = append(events, event)
+		}
+	}
+	return
+}
+
+func (p *API) autoUpdateTool(ctx context.Context, toolName string, strategy AutoUpdateStrategy) (event ToolUpdatedEvent, updated bool, err error) {
+	if _, _, locked := p.GetLockedVersion(toolName); locked {
+		return
+	}
+	if version, _ := p.GetPinnedVersion(toolName); version != "" {
+		return
+	}
+	installed, err := p.GetInstalledVersions(toolName)
+	if err != nil || len(installed) == 0 {
+		return
+	}
+	newest := installed[0]
+	for _, version := range installed[1:] {
+		if config.CompareVersions(version, newest) > 0 {
+			newest = version
+		}
+	}
+
+	tool, err := p.GetToolLatest(toolName, strategy.Channel)
+	if err != nil {
+		return
+	}
+	if containsString(installed, tool.GetVersion()) || config.CompareVersions(tool.GetVersion(), newest) <= 0 {
+		return
+	}
+	if err = p.Install(ctx, toolName, tool.GetVersion(), nil); err != nil {
+		return
+	}
+
+	event = ToolUpdatedEvent{
+		ToolName:   toolName,
+		OldVersion: newest,
+		NewVersion: tool.GetVersion(),
+		Tool:       tool,
+	}
+	updated = true
+	p.updateLock.Lock()
+	handlers := append([]ToolUpdatedHandler(nil), p.updatedHandlers...)
+	p.updateLock.Unlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+
+	if strategy.RemoveOldVersions {
+		p.removeOldVersions(toolName, installed, event.NewVersion)
+	}
+	return
+}
+
+// 删除比 version 旧的已安装版本，仍被其他工具依赖或仍在运行的版本会保留，失败只记录日志
+func (p *API) removeOldVersions(toolName string, installed []string, version string) {
+	for _, oldVersion := range installed {
+		if config.CompareVersions(oldVersion, version) >= 0 {
+			continue
+		}
+		if tool, err := p.GetToolWithVersion(toolName, oldVersion); err == nil {
+			if downloaded, ok := tool.(*DownloadedTool); ok &&
+				len(runningProcessesIn(filepath.FromSlash(downloaded.GetToolFolder()))) > 0 {
+				continue
+			}
+		}
+		if err := p.Uninstall(toolName, oldVersion); err != nil {
+			log.Printf("failed to remove %s %s: %v\n", toolName, oldVersion, err)
+		}
+	}
+}
@@ -1,17 +1,21 @@
 package tools
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/kira1928/remotetools/pkg/config"
 )
 
 type BaseTool struct {
 	*config.ToolConfig
+	scope *Scope
 }
 
 func NewBaseTool(config *config.ToolConfig) *BaseTool {
@@ -20,50 +24,219 @@ func NewBaseTool(config *config.ToolConfig) *BaseTool {
 	}
 }
 
+// GetScope returns the scope selected for this tool: the one chosen on install,
+// then the one from the config entry, then the default scope.
+func (p *BaseTool) GetScope() Scope {
+	if p.scope != nil {
+		return *p.scope
+	}
+	return Scope(p.ToolConfig.Scope)
+}
+
+func (p *BaseTool) SetScope(scope Scope) {
+	p.scope = &scope
+}
+
+func (p *BaseTool) getRootFolder() string {
+	folder, err := GetScopeFolder(p.GetScope())
+	if err != nil {
+		return GetToolFolder()
+	}
+	return folder
+}
+
 func (p *BaseTool) GetToolFolder() string {
-	return fmt.Sprintf("%s/%s/%s/%s/%s", GetToolFolder(), runtime.GOOS, runtime.GOARCH, p.ToolName, p.Version)
+	return fmt.Sprintf("%s/%s/%s/%s/%s", p.getRootFolder(), runtime.GOOS, runtime.GOARCH, p.ToolName, p.Version)
 }
 
 func (p *BaseTool) GetToolPath() string {
-	return filepath.Join(p.GetToolFolder(), p.PathToEntry.Value)
+	return filepath.Join(p.GetToolFolder(), expandEnvIfSet(p.Expand(p.PathToEntry.Value)))
 }
 
+// expandEnvIfSet 用于不能返回错误的地方，如入口路径，引用的环境变量未设置时返回原样的 s，
+// 安装时会通过 config.ExpandEnv 报告错误
+func expandEnvIfSet(s string) string {
+	if expanded, err := config.ExpandEnv(s); err == nil {
+		return expanded
+	}
+	return s
+}
+
+// DoesToolExist reports whether the entry exists and the install was completed,
+// see CompleteMarkerFile, and the files match the manifest with
+// SetStrictInstallCheck.
 func (p *BaseTool) DoesToolExist() bool {
-	_, err := os.Stat(p.GetToolPath())
-	return err == nil
+	if _, err := os.Stat(p.GetToolPath()); err != nil {
+		return false
+	}
+	toolFolder := filepath.FromSlash(p.GetToolFolder())
+	if hasCompleteMarker(toolFolder) {
+		return passesStrictCheck(toolFolder)
+	}
+	platformFolder := filepath.Dir(filepath.Dir(toolFolder))
+	if !migrateLegacyInstalls(platformFolder) {
+		return true
+	}
+	return hasCompleteMarker(toolFolder)
 }
 
 func (p *BaseTool) Install() error {
 	return nil
 }
 
+func (p *BaseTool) InstallContext(ctx context.Context) error {
+	return nil
+}
+
 func (p *BaseTool) CreateExecuteCmd(args ...string) (cmd *exec.Cmd, err error) {
+	return p.CreateExecuteCmdContext(context.Background(), args...)
+}
+
+// CreateExecuteCmdContext creates the command with exec.CommandContext, so the
+// process is killed when ctx is done.
+func (p *BaseTool) CreateExecuteCmdContext(ctx context.Context, args ...string) (cmd *exec.Cmd, err error) {
+	if !p.IsExecutable() {
+		return nil, fmt.Errorf("tool %s is not executable", p.ToolName)
+	}
+
 	// check if tool exists
 	if !p.DoesToolExist() {
 		return nil, fmt.Errorf("tool %s not found", p.ToolName)
 	}
 
 	// create the command
-	cmd = exec.Command(p.GetToolPath(), args...)
+	cmd = exec.CommandContext(ctx, longPath(p.GetToolPath()), args...)
+	if env := p.getToolEnv(); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Dir = resolveWorkingDir(p.ToolConfig, filepath.FromSlash(p.GetToolFolder()))
+	touchLastUsed(filepath.FromSlash(p.GetToolFolder()))
+
+	return
+}
+
+func (p *BaseTool) getToolEnv() []string {
+	return buildToolEnv(p.ToolConfig, filepath.FromSlash(p.GetToolFolder()))
+}
+
+func (p *BaseTool) CreateExecuteCmdWithOptions(opts ExecOptions, args ...string) (cmd *exec.Cmd, err error) {
+	return p.createExecuteCmdWithOptions(context.Background(), opts, args...)
+}
 
+func (p *BaseTool) createExecuteCmdWithOptions(ctx context.Context, opts ExecOptions, args ...string) (cmd *exec.Cmd, err error) {
+	cmd, err = p.CreateExecuteCmdContext(ctx, args...)
+	if err != nil {
+		return
+	}
+	applyExecOptions(cmd, opts, p.EnvWhitelist, p.getToolEnv())
+	applyExecDir(cmd, opts, filepath.FromSlash(p.GetToolFolder()))
 	return
 }
 
 func (p *BaseTool) Execute(args ...string) (err error) {
-	// create the command
-	cmd, err := p.CreateExecuteCmd(args...)
+	return p.executeWithOptions(context.Background(), ExecOptions{}, args...)
+}
+
+func (p *BaseTool) ExecuteContext(ctx context.Context, args ...string) (err error) {
+	return p.executeWithOptions(ctx, ExecOptions{}, args...)
+}
+
+// ExecuteWithTimeout runs the tool like ExecuteContext with a deadline of d,
+// a *TimeoutError is returned when it is exceeded.
+func (p *BaseTool) ExecuteWithTimeout(d time.Duration, args ...string) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return p.executeWithOptions(ctx, ExecOptions{}, args...)
+}
+
+// ExecuteStream starts the tool and returns its output as streams, see ExecStream.
+func (p *BaseTool) ExecuteStream(args ...string) (stream *ExecStream, err error) {
+	return p.executeStream(context.Background(), ExecOptions{}, args...)
+}
+
+// ExecuteStreamContext is ExecuteStream with options, ctx stops the tool like ExecuteContext.
+func (p *BaseTool) ExecuteStreamContext(ctx context.Context, opts ExecOptions, args ...string) (stream *ExecStream, err error) {
+	return p.executeStream(ctx, opts, args...)
+}
+
+// ExecuteStreamFunc runs the tool and passes every line of its output to
+// onStdout and onStderr, either can be nil.
+func (p *BaseTool) ExecuteStreamFunc(onStdout, onStderr OutputHandler, args ...string) (exitCode int, err error) {
+	stream, err := p.executeStream(context.Background(), ExecOptions{}, args...)
+	if err != nil {
+		return exitCodeOf(err), err
+	}
+	return consumeStream(stream, onStdout, onStderr)
+}
+
+func (p *BaseTool) executeStream(ctx context.Context, opts ExecOptions, args ...string) (stream *ExecStream, err error) {
+	cmd, err := p.createExecuteCmdWithOptions(context.Background(), opts, args...)
+	if err != nil {
+		return
+	}
+	return startStream(ctx, cmd, filepath.FromSlash(p.GetToolFolder()), p.ToolName, p.Version, args)
+}
+
+func (p *BaseTool) ExecuteWithOptions(opts ExecOptions, args ...string) (err error) {
+	return p.executeWithOptions(context.Background(), opts, args...)
+}
+
+func (p *BaseTool) executeWithOptions(ctx context.Context, opts ExecOptions, args ...string) (err error) {
+	// create the command, ctx is handled by runTracked to stop the process gracefully
+	cmd, err := p.createExecuteCmdWithOptions(context.Background(), opts, args...)
 	if err != nil {
 		return
 	}
 
 	// execute the command
-	if err = cmd.Run(); err != nil {
-		return err
+	startedAt := time.Now()
+	err = runTracked(ctx, cmd, filepath.FromSlash(p.GetToolFolder()))
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = &TimeoutError{ToolName: p.ToolName, Version: p.Version}
 	}
+	statsStore.record(p.ToolName, p.Version, args, startedAt, err)
 
 	return
 }
 
+// ExecAndGetInfoString runs the tool with the configured printInfoCmd and returns its output.
+func (p *BaseTool) ExecAndGetInfoString() (string, error) {
+	if len(p.PrintInfoCmd) == 0 {
+		return "", fmt.Errorf("tool %s has no printInfoCmd configured", p.ToolName)
+	}
+	cmd, err := p.CreateExecuteCmd(p.PrintInfoCmd...)
+	if err != nil {
+		return "", err
+	}
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// GetDiskUsage returns the total size of the files in the tool folder.
+func (p *BaseTool) GetDiskUsage() (int64, error) {
+	return DirSize(p.GetToolFolder())
+}
+
+func (p *BaseTool) GetConfiguredEntry() string {
+	return expandEnvIfSet(p.Expand(p.PathToEntry.Value))
+}
+
+func (p *BaseTool) GetDownloadURLForPlatform(goos, goarch string) (string, error) {
+	url, err := p.DownloadURL.ValueFor(goos, goarch)
+	return p.ExpandFor(url, goos, goarch), err
+}
+
+func (p *BaseTool) GetMetadata() ToolMetadata {
+	return newToolMetadata(p.ToolConfig)
+}
+
 func (p *BaseTool) GetVersion() string {
 	return p.Version
 }
+
+func (p *BaseTool) GetInstallRecord() (*InstallRecord, error) {
+	if !p.DoesToolExist() {
+		return nil, fmt.Errorf("tool %s is not installed", p.ToolName)
+	}
+	return readInstallRecord(filepath.FromSlash(p.GetToolFolder()))
+}
@@ -0,0 +1,361 @@
+package tools
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// BundleManifestFile 是工具包中第一个条目，记录包中的工具，工具目录位于
+// tools/<tool>/<version>/ 下
+const BundleManifestFile = "bundle.json"
+
+const bundleToolsFolder = "tools/"
+
+type BundleEntry struct {
+	Tool    string `json:"tool"`
+	Version string `json:"version"`
+	// config of the tool version without credentials (token, headers)
+	Config *config.ToolConfig `json:"config,omitempty"`
+	// record of the original install
+	Record *InstallRecord `json:"record,omitempty"`
+}
+
+type BundleManifest struct {
+	CreatedAt time.Time     `json:"createdAt"`
+	Platform  Platform      `json:"platform"`
+	Tools     []BundleEntry `json:"tools"`
+}
+
+type ImportResult struct {
+	Tool    string
+	Version string
+	// the version was already installed and left untouched
+	Skipped bool
+}
+
+// Export writes the installed tools, together with the installed tools they
+// depend on, as a tar bundle to w, see Import. The tool folders are packed as
+// they are, including the files written by postInstall.
+func (p *API) Export(w io.Writer, tools []Tool) (manifest BundleManifest, err error) {
+	manifest.CreatedAt = time.Now().UTC()
+	manifest.Platform = CurrentPlatform()
+
+	var folders []string
+	added := make(map[string]bool)
+	add := func(tool Tool) error {
+		key := tool.GetMetadata().Name + "@" + tool.GetVersion()
+		if added[key] {
+			return nil
+		}
+		added[key] = true
+		downloaded, ok := tool.(*DownloadedTool)
+		if !ok {
+			return fmt.Errorf("tool %s uses a dev override and cannot be exported", key)
+		}
+		if !downloaded.DoesToolExist() {
+			return fmt.Errorf("tool %s is not installed", key)
+		}
+		folder := filepath.FromSlash(downloaded.GetToolFolder())
+		entry := BundleEntry{Tool: downloaded.ToolName, Version: downloaded.Version}
+		toolConfig := *downloaded.ToolConfig
+		toolConfig.Token = ""
+		toolConfig.Headers = nil
+		entry.Config = &toolConfig
+		entry.Record, _ = readInstallRecord(folder)
+		manifest.Tools = append(manifest.Tools, entry)
+		folders = append(folders, folder)
+		return nil
+	}
+	for _, tool := range tools {
+		dependencies, err := p.dependencyOrder(tool)
+		if err != nil {
+			return manifest, err
+		}
+		for _, dependency := range append(dependencies, tool) {
+			if err = add(dependency); err != nil {
+				return manifest, err
+			}
+		}
+	}
+
+	tw := tar.NewWriter(w)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:     BundleManifestFile,
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  manifest.CreatedAt,
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return
+	}
+	if _, err = tw.Write(data); err != nil {
+		return
+	}
+	for i, entry := range manifest.Tools {
+		prefix := bundleToolsFolder + entry.Tool + "/" + entry.Version + "/"
+		if err = writeBundleFolder(tw, folders[i], prefix); err != nil {
+			return manifest, fmt.Errorf("failed to export tool %s %s: %w", entry.Tool, entry.Version, err)
+		}
+	}
+	err = tw.Close()
+	return
+}
+
+func writeBundleFolder(tw *tar.Writer, folder, prefix string) error {
+	return filepath.WalkDir(folder, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == folder {
+			return nil
+		}
+		rel, err := filepath.Rel(folder, filePath)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{
+			Name:    prefix + filepath.ToSlash(rel),
+			Mode:    int64(info.Mode().Perm()),
+			ModTime: info.ModTime(),
+			Format:  tar.FormatPAX,
+		}
+		switch {
+		case info.IsDir():
+			header.Name += "/"
+			header.Typeflag = tar.TypeDir
+		case info.Mode()&os.ModeSymlink != 0:
+			header.Typeflag = tar.TypeSymlink
+			if header.Linkname, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			header.Typeflag = tar.TypeReg
+			header.Size = info.Size()
+		default:
+			return fmt.Errorf("unsupported file type: %s", filePath)
+		}
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil
+		}
+		return copyFileTo(tw, filePath)
+	})
+}
+
+// Import restores the tools of a bundle written by Export. The bundle has to
+// be exported for the current platform. Each tool is extracted next to its
+// tool folder first and renamed into place, versions that are already
+// installed are skipped. postInstall commands are not run again.
+func (p *API) Import(r io.Reader) (results []ImportResult, err error) {
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	if header.Name != BundleManifestFile {
+		return nil, fmt.Errorf("not a tool bundle, the first entry is %s instead of %s", header.Name, BundleManifestFile)
+	}
+	var manifest BundleManifest
+	if err = json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", BundleManifestFile, err)
+	}
+	if manifest.Platform != CurrentPlatform() {
+		return nil, fmt.Errorf("the bundle was exported for %s, not %s", manifest.Platform, CurrentPlatform())
+	}
+
+	// tool@version -> folder the tool is extracted to
+	stagingFolders := make(map[string]string)
+	toolFolders := make(map[string]string)
+	for _, entry := range manifest.Tools {
+		if !isBundlePathElement(entry.Tool) || !isBundlePathElement(entry.Version) {
+			return nil, fmt.Errorf("invalid tool %s %s in bundle", entry.Tool, entry.Version)
+		}
+		toolFolders[entry.Tool+"@"+entry.Version] = p.importFolder(entry.Tool, entry.Version)
+	}
+	defer func() {
+		for _, stagingFolder := range stagingFolders {
+			os.RemoveAll(stagingFolder)
+		}
+	}()
+
+	for {
+		header, err = tr.Next()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		name := strings.TrimPrefix(header.Name, bundleToolsFolder)
+		parts := strings.SplitN(name, "/", 3)
+		if name == header.Name || len(parts) < 3 {
+			return nil, fmt.Errorf("unexpected entry %s in bundle", header.Name)
+		}
+		key := parts[0] + "@" + parts[1]
+		toolFolder, ok := toolFolders[key]
+		if !ok {
+			return nil, fmt.Errorf("entry %s does not belong to a tool of the bundle", header.Name)
+		}
+		stagingFolder, ok := stagingFolders[key]
+		if !ok {
+			if err = os.MkdirAll(filepath.Dir(toolFolder), 0755); err != nil {
+				return nil, err
+			}
+			if stagingFolder, err = os.MkdirTemp(filepath.Dir(toolFolder), ".tmp_"+parts[1]+"_"); err != nil {
+				return nil, err
+			}
+			stagingFolders[key] = stagingFolder
+			if err = os.Chmod(stagingFolder, 0755); err != nil {
+				return nil, err
+			}
+		}
+		if parts[2] == "" {
+			continue
+		}
+		if err = extractBundleEntry(tr, header, stagingFolder, parts[2]); err != nil {
+			return nil, fmt.Errorf("failed to import tool %s: %w", key, err)
+		}
+	}
+
+	for _, entry := range manifest.Tools {
+		key := entry.Tool + "@" + entry.Version
+		stagingFolder, ok := stagingFolders[key]
+		if !ok {
+			return results, fmt.Errorf("tool %s has no files in the bundle", key)
+		}
+		skipped, err := installBundleFolder(key, stagingFolder, toolFolders[key], entry.Record)
+		if err != nil {
+			return results, fmt.Errorf("failed to import tool %s: %w", key, err)
+		}
+		results = append(results, ImportResult{Tool: entry.Tool, Version: entry.Version, Skipped: skipped})
+	}
+	return
+}
+
+// importFolder 返回工具版本应导入到的目录，配置中没有的工具导入到默认的根目录
+func (p *API) importFolder(toolName, version string) string {
+	if tool, err := p.GetToolWithVersion(toolName, version); err == nil {
+		if downloaded, ok := tool.(*DownloadedTool); ok {
+			return filepath.FromSlash(downloaded.GetToolFolder())
+		}
+	}
+	return filepath.Join(GetToolFolder(), CurrentPlatform().OS, CurrentPlatform().Arch, toolName, version)
+}
+
+func isBundlePathElement(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\:`)
+}
+
+func extractBundleEntry(tr *tar.Reader, header *tar.Header, dest, name string) error {
+	targetPath, err := archiveEntryPath(dest, name)
+	if err != nil {
+		return err
+	}
+	// 已解压的符号链接可能指向 dest 之外，不能经过它们写入后面的条目
+	if err = checkNoSymlinkInPath(dest, targetPath); err != nil {
+		return err
+	}
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(targetPath, header.FileInfo().Mode().Perm()|0700)
+	case tar.TypeSymlink:
+		// 只允许指向工具目录内的相对链接
+		linkTarget := path.Join(path.Dir(name), header.Linkname)
+		if path.IsAbs(header.Linkname) || linkTarget == ".." || strings.HasPrefix(linkTarget, "../") {
+			return fmt.Errorf("symlink %s -> %s points outside of the tool folder", name, header.Linkname)
+		}
+		if err = os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return err
+		}
+		return os.Symlink(header.Linkname, targetPath)
+	case tar.TypeReg:
+		if err = os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode().Perm())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, tr)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	default:
+		return fmt.Errorf("unsupported entry type %c of %s", header.Typeflag, name)
+	}
+}
+
+// checkNoSymlinkInPath 检查 targetPath 在 dest 之下的各级路径中没有符号链接，
+// 不存在的部分由调用方创建，不需要检查
+func checkNoSymlinkInPath(dest, targetPath string) error {
+	rel, err := filepath.Rel(dest, targetPath)
+	if err != nil {
+		return err
+	}
+	current := dest
+	for _, element := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, element)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is written through the symlink %s", targetPath, current)
+		}
+	}
+	return nil
+}
+
+// installBundleFolder 把解压好的目录改名为工具目录，并写入新的安装记录
+func installBundleFolder(key, stagingFolder, toolFolder string, original *InstallRecord) (skipped bool, err error) {
+	lock := lockInstall(key)
+	defer lock.Unlock()
+
+	if hasCompleteMarker(toolFolder) {
+		return true, nil
+	}
+	record := newInstallRecord("bundle", "")
+	if original != nil {
+		record.FileName = original.FileName
+		record.SHA256 = original.SHA256
+		record.Commit = original.Commit
+	}
+	if err = writeCompleteMarker(stagingFolder, record); err != nil {
+		return
+	}
+	if err = os.RemoveAll(toolFolder); err != nil {
+		return
+	}
+	if err = os.Rename(stagingFolder, toolFolder); err != nil {
+		return
+	}
+	dedupeToolFolder(toolFolder)
+	return
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 后面的条目经过前面解压的符号链接写入时，不能写到工具目录之外
+func TestImportSymlinkChain(t *testing.T) {
+	root := t.TempDir()
+	toolRoot := filepath.Join(root, "tools")
+	oldToolFolder := toolFolder
+	SetToolFolder(toolRoot)
+	defer SetToolFolder(oldToolFolder)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	manifest, err := json.Marshal(BundleManifest{
+		Platform: CurrentPlatform(),
+		Tools:    []BundleEntry{{Tool: "evil", Version: "1.0"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeEntry := func(header *tar.Header, content []byte) {
+		header.Size = int64(len(content))
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeEntry(&tar.Header{Name: BundleManifestFile, Mode: 0644, Typeflag: tar.TypeReg}, manifest)
+	prefix := bundleToolsFolder + "evil/1.0/"
+	links := [][2]string{{"a", "."}, {"a/b", ".."}, {"b/c", ".."}, {"b/c/d", ".."}, {"b/c/d/e", ".."}}
+	for _, link := range links {
+		writeEntry(&tar.Header{Name: prefix + link[0], Linkname: link[1], Mode: 0777, Typeflag: tar.TypeSymlink}, nil)
+	}
+	writeEntry(&tar.Header{Name: prefix + "b/c/d/e/ESCAPED.txt", Mode: 0644, Typeflag: tar.TypeReg}, []byte("escaped"))
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	api := &API{toolInstances: make(map[string]Tool)}
+	if _, err = api.Import(&buf); err == nil {
+		t.Fatal("importing the bundle succeeded, want an error")
+	}
+	filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+		if err == nil && info.Name() == "ESCAPED.txt" {
+			t.Errorf("ESCAPED.txt was written to %s", filePath)
+		}
+		return nil
+	})
+	if _, err = os.Lstat(filepath.Join(filepath.Dir(root), "ESCAPED.txt")); !os.IsNotExist(err) {
+		t.Errorf("ESCAPED.txt was written outside of the test folder: %v", err)
+	}
+}
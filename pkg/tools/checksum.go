@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned when a downloaded artifact does not match its sha256.
+var ErrChecksumMismatch = errors.New("sha256 mismatch")
+
+// 校验文件的大小上限，SHASUMS 文件通常只有几 KB
+const maxChecksumFileSize = 1 << 20
+
+// fetchSmallFile 下载配置中的校验文件、签名等小文件，超过 maxChecksumFileSize 的部分被忽略。
+// url 中的 ${NAME} 在请求前才展开
+func fetchSmallFile(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	resp, err := openConfiguredDownload(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, maxChecksumFileSize))
+}
+
+// fetchChecksum downloads a checksum file and returns the sha256 listed for fileName.
+func fetchChecksum(ctx context.Context, checksumURL, fileName string, header http.Header) (string, error) {
+	data, err := fetchSmallFile(ctx, checksumURL, header)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksum file: %w", err)
+	}
+	return parseChecksumFile(data, fileName)
+}
+
+// parseChecksumFile 支持以下格式：
+//
+//	<sha256>                      单文件的 .sha256
+//	<sha256>  <file> / <sha256> *<file>   sha256sum 输出的 SHASUMS 文件
+//	SHA256 (<file>) = <sha256>    BSD 风格
+func parseChecksumFile(data []byte, fileName string) (string, error) {
+	var single []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "SHA256 (") {
+			name, sum, ok := strings.Cut(strings.TrimPrefix(line, "SHA256 ("), ") = ")
+			if ok && path.Base(name) == fileName && isSHA256Hex(sum) {
+				return strings.ToLower(sum), nil
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 1 && isSHA256Hex(fields[0]):
+			single = append(single, fields[0])
+		case len(fields) >= 2 && isSHA256Hex(fields[0]):
+			name := strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+			if path.Base(name) == fileName {
+				return strings.ToLower(fields[0]), nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if len(single) == 1 {
+		return strings.ToLower(single[0]), nil
+	}
+	return "", fmt.Errorf("no sha256 checksum for %s found in checksum file", fileName)
+}
+
+func isSHA256Hex(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func verifySHA256(path, expected string) error {
+	actual, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, filepath.Base(path), expected, actual)
+	}
+	return nil
+}
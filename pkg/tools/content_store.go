@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/kira1928/remotetools/pkg/tools/storage"
+)
+
+// ContentStoreFolder 是根目录下按 sha256 存放共享文件的目录，见 storage 包
+const ContentStoreFolder = ".store"
+
+var dedupeEnabled atomic.Bool
+
+// SetDedupe makes installs link identical files of different tools and
+// versions to one copy in the content store of their root, see the storage
+// package. It is off by default because the linked files share their data,
+// tools must not modify their own files in place. Dedupe moves existing
+// installs into the store.
+func SetDedupe(enabled bool) {
+	dedupeEnabled.Store(enabled)
+}
+
+// SetDedupe sets whether installs are deduplicated, see the package level SetDedupe.
+func (p *API) SetDedupe(enabled bool) {
+	SetDedupe(enabled)
+}
+
+func IsDedupeEnabled() bool {
+	return dedupeEnabled.Load()
+}
+
+func contentStore(root string) *storage.Store {
+	return storage.New(filepath.Join(root, ContentStoreFolder))
+}
+
+// 工具目录为 <root>/<os>/<arch>/<tool>/<version>
+func rootOfToolFolder(toolFolder string) string {
+	return filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(toolFolder))))
+}
+
+// 每个版本自己的元数据文件不能共享，如 LastUsedFile 的修改时间
+func isToolMetadataFile(rel string) bool {
+	return rel == CompleteMarkerFile || rel == LastUsedFile || rel == ManifestFile
+}
+
+// dedupeToolFolder 在开启去重时把刚安装的工具目录链接到内容存储，失败只记录日志
+func dedupeToolFolder(toolFolder string) {
+	if !IsDedupeEnabled() {
+		return
+	}
+	if _, err := contentStore(rootOfToolFolder(toolFolder)).Dedupe(toolFolder, isToolMetadataFile); err != nil {
+		log.Printf("failed to dedupe %s: %v\n", toolFolder, err)
+	}
+}
+
+// pruneContentStore 删除不再被任何工具目录引用的文件
+func pruneContentStore(root string) {
+	if _, err := os.Stat(filepath.Join(root, ContentStoreFolder)); err != nil {
+		return
+	}
+	if _, _, err := contentStore(root).Prune(); err != nil {
+		log.Printf("failed to prune the content store of %s: %v\n", root, err)
+	}
+}
+
+// Dedupe links the files of every installed version of the configured tools
+// to the content store of their root, which migrates installs made before
+// SetDedupe was turned on. It works whether or not SetDedupe is on.
+func (p *API) Dedupe() (stats storage.Stats, err error) {
+	for _, toolName := range p.ToolNames() {
+		versions, _ := p.GetInstalledVersions(toolName)
+		for _, version := range versions {
+			tool, err := p.GetToolWithVersion(toolName, version)
+			if err != nil {
+				continue
+			}
+			downloaded, ok := tool.(*DownloadedTool)
+			if !ok {
+				continue
+			}
+			toolFolder := filepath.FromSlash(downloaded.GetToolFolder())
+			lock := lockInstall(toolName + "@" + version)
+			toolStats, err := contentStore(rootOfToolFolder(toolFolder)).Dedupe(toolFolder, isToolMetadataFile)
+			lock.Unlock()
+			stats.Add(toolStats)
+			if err != nil {
+				return stats, err
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,24 @@
+package tools
+
+import (
+	"context"
+)
+
+type apiContextKey struct{}
+
+// WithAPI returns a copy of ctx carrying api, so request scoped code can use a
+// specific API instance (root, config) instead of the package singleton.
+func WithAPI(ctx context.Context, api *API) context.Context {
+	return context.WithValue(ctx, apiContextKey{}, api)
+}
+
+// FromContext returns the API stored by WithAPI, or the singleton returned by
+// Get when ctx carries none.
+func FromContext(ctx context.Context) *API {
+	if ctx != nil {
+		if api, ok := ctx.Value(apiContextKey{}).(*API); ok && api != nil {
+			return api
+		}
+	}
+	return Get()
+}
@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// resolveDependency 解析依赖声明 "tool"、"tool@version" 或 "tool@<约束>"，
+// 约束按 GetToolByConstraint 选择版本
+func (p *API) resolveDependency(dependency string) (tool Tool, err error) {
+	toolName, spec, _ := strings.Cut(dependency, "@")
+	switch {
+	case spec == "":
+		tool, err = p.GetTool(toolName)
+		if err == nil && tool == nil {
+			err = fmt.Errorf("tool %s not found in config", toolName)
+		}
+		return
+	case p.isKnownVersion(toolName, spec):
+		return p.GetToolWithVersion(toolName, spec)
+	default:
+		return p.GetToolByConstraint(toolName, spec)
+	}
+}
+
+// 版本已配置或已安装时按具体版本处理，而不是约束
+func (p *API) isKnownVersion(toolName, version string) bool {
+	p.lock.RLock()
+	_, configured := p.config.GetVersionConfig(toolName, version)
+	p.lock.RUnlock()
+	if configured {
+		return true
+	}
+	installed, _ := p.GetInstalledVersions(toolName)
+	return containsString(installed, version)
+}
+
+// dependencyMatches reports whether version of toolName satisfies dependency.
+func (p *API) dependencyMatches(dependency, toolName, version string) bool {
+	name, spec, _ := strings.Cut(dependency, "@")
+	if name != toolName {
+		return false
+	}
+	switch {
+	case spec == "":
+		tool, err := p.GetTool(toolName)
+		return err == nil && tool != nil && tool.GetVersion() == version
+	case spec == version:
+		return true
+	default:
+		matched, err := config.MatchVersions([]string{version}, spec)
+		return err == nil && len(matched) > 0
+	}
+}
+
+// dependencyOrder returns the dependencies of tool, direct and indirect, in the
+// order they have to be installed. Dependency cycles are reported as an error.
+func (p *API) dependencyOrder(tool Tool) (order []Tool, err error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var visit func(tool Tool, path []string) error
+	visit = func(tool Tool, path []string) error {
+		metadata := tool.GetMetadata()
+		key := metadata.Name + "@" + tool.GetVersion()
+		switch state[key] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, key), " -> "))
+		}
+		state[key] = visiting
+		for _, dependency := range metadata.Dependencies {
+			dependencyTool, err := p.resolveDependency(dependency)
+			if err != nil {
+				return fmt.Errorf("failed to resolve dependency %s of %s: %w", dependency, key, err)
+			}
+			if err = visit(dependencyTool, append(path, key)); err != nil {
+				return err
+			}
+		}
+		state[key] = visited
+		order = append(order, tool)
+		return nil
+	}
+
+	if err = visit(tool, nil); err != nil {
+		return nil, err
+	}
+	// the last one is tool itself
+	return order[:len(order)-1], nil
+}
+
+// GetDependents returns the installed tools, as "tool@version", that depend on
+// the given version of a tool and have no other installed version to use.
+func (p *API) GetDependents(toolName, version string) (dependents []string) {
+	installedVersions, _ := p.GetInstalledVersions(toolName)
+	for _, name := range p.ToolNames() {
+		versions, _ := p.GetInstalledVersions(name)
+		for _, installedVersion := range versions {
+			tool, err := p.GetToolWithVersion(name, installedVersion)
+			if err != nil {
+				continue
+			}
+			for _, dependency := range tool.GetMetadata().Dependencies {
+				if !p.dependencyMatches(dependency, toolName, version) {
+					continue
+				}
+				// 还有其他已安装的版本满足依赖时不算被依赖
+				satisfied := false
+				for _, other := range installedVersions {
+					if other != version && p.dependencyMatches(dependency, toolName, other) {
+						satisfied = true
+						break
+					}
+				}
+				if !satisfied {
+					dependents = append(dependents, name+"@"+installedVersion)
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return
+}
+
+// Uninstall removes an installed version of a tool, the default version when
+// version is empty. It fails while installed tools still depend on it, see
+// GetDependents. The preUninstall commands run first, then the processes of
+// the version started by Execute are killed.
+func (p *API) Uninstall(toolName, version string) error {
+	var tool Tool
+	var err error
+	if version == "" {
+		tool, err = p.GetTool(toolName)
+		if err == nil && tool == nil {
+			err = fmt.Errorf("tool %s not found in config", toolName)
+		}
+	} else {
+		tool, err = p.GetToolWithVersion(toolName, version)
+	}
+	if err != nil {
+		return err
+	}
+	version = tool.GetVersion()
+
+	downloaded, ok := tool.(*DownloadedTool)
+	if !ok {
+		return fmt.Errorf("tool %s %s uses a dev override and cannot be uninstalled", toolName, version)
+	}
+	if !downloaded.DoesToolExist() {
+		return fmt.Errorf("tool %s %s is not installed", toolName, version)
+	}
+	if dependents := p.GetDependents(toolName, version); len(dependents) > 0 {
+		return fmt.Errorf("tool %s %s is required by %s", toolName, version, strings.Join(dependents, ", "))
+	}
+	toolFolder := filepath.FromSlash(downloaded.GetToolFolder())
+	if err = downloaded.runHooks(context.Background(), "preUninstall", downloaded.PreUninstall, toolFolder); err != nil {
+		return err
+	}
+	if err = killToolProcesses(toolFolder); err != nil {
+		return fmt.Errorf("failed to stop tool %s %s: %w", toolName, version, err)
+	}
+	if err = removeToolFolder(toolFolder); err != nil {
+		return err
+	}
+	pruneContentStore(rootOfToolFolder(toolFolder))
+	return nil
+}
@@ -0,0 +1,425 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// DevOverridesFile 是开发者本地覆盖工具路径的配置文件，格式为
+// {"toolName": "/path/to/tool"} 或 {"toolName": {"path": "...", "build": "...", "watch": "..."}}，
+// 键也可以是 toolName@version，只覆盖该版本。库不会自动读取它，
+// 由 CLI 或调用方通过 LoadDevToolOverrides 显式加载
+const DevOverridesFile = ".remotetools-dev.json"
+
+// DevOverrideEnvPrefix + 大写的工具名（非字母数字替换为 _）可以覆盖该工具的路径，
+// 如 REMOTETOOLS_DEV_DOTNET 或只覆盖一个版本的 REMOTETOOLS_DEV_DOTNET_8_0_5，
+// 再加 _BUILD 后缀可以指定构建命令
+const DevOverrideEnvPrefix = "REMOTETOOLS_DEV_"
+
+type DevOverride struct {
+	Path string `json:"path"`
+	// BuildCommand is run through the shell in WatchDir whenever a file under
+	// WatchDir is newer than the binary.
+	BuildCommand string `json:"build"`
+	// WatchDir defaults to Path when it is a folder, or the folder containing it.
+	WatchDir string `json:"watch"`
+}
+
+func (p *DevOverride) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*p = DevOverride{Path: path}
+		return nil
+	}
+	type devOverride DevOverride
+	return json.Unmarshal(data, (*devOverride)(p))
+}
+
+var (
+	devOverridesLock sync.RWMutex
+	devOverrides     = map[string]DevOverride{}
+	devFileOverrides = map[string]DevOverride{}
+	devBuildLock     sync.Mutex
+)
+
+// SetDevToolOverride makes GetTool return the local binary at path instead of
+// the downloaded tool. It takes precedence over env vars and the overrides file.
+func SetDevToolOverride(toolName, path string) {
+	devOverridesLock.Lock()
+	defer devOverridesLock.Unlock()
+	override := devOverrides[toolName]
+	override.Path = path
+	devOverrides[toolName] = override
+}
+
+// SetDevToolBuild sets the command that rebuilds an overridden tool when its
+// sources change. An empty watchDir watches the folder of the override path.
+func SetDevToolBuild(toolName, buildCommand, watchDir string) {
+	devOverridesLock.Lock()
+	defer devOverridesLock.Unlock()
+	override := devOverrides[toolName]
+	override.BuildCommand = buildCommand
+	override.WatchDir = watchDir
+	devOverrides[toolName] = override
+}
+
+func RemoveDevToolOverride(toolName string) {
+	devOverridesLock.Lock()
+	defer devOverridesLock.Unlock()
+	delete(devOverrides, toolName)
+}
+
+// LoadDevToolOverrides reads overrides from a file in the DevOverridesFile format.
+// Relative paths are resolved against the folder of the file.
+func LoadDevToolOverrides(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	overrides := make(map[string]DevOverride)
+	if err = json.Unmarshal(data, &overrides); err != nil {
+		return fmt.Errorf("invalid dev overrides file %s: %w", path, err)
+	}
+	for toolName, override := range overrides {
+		if override.Path != "" && !filepath.IsAbs(override.Path) {
+			override.Path = filepath.Join(filepath.Dir(path), override.Path)
+		}
+		if override.WatchDir != "" && !filepath.IsAbs(override.WatchDir) {
+			override.WatchDir = filepath.Join(filepath.Dir(path), override.WatchDir)
+		}
+		overrides[toolName] = override
+	}
+
+	devOverridesLock.Lock()
+	defer devOverridesLock.Unlock()
+	devFileOverrides = overrides
+	return nil
+}
+
+// LoadDevToolOverrides reads overrides from a file, see the package level
+// LoadDevToolOverrides.
+func (p *API) LoadDevToolOverrides(path string) error {
+	return LoadDevToolOverrides(path)
+}
+
+func devOverrideEnvName(toolName string) string {
+	return DevOverrideEnvPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, toolName)
+}
+
+func GetDevToolOverride(toolName string) (override DevOverride, ok bool) {
+	devOverridesLock.RLock()
+	defer devOverridesLock.RUnlock()
+	if override, ok = devOverrides[toolName]; ok && override.Path != "" {
+		return
+	}
+	envName := devOverrideEnvName(toolName)
+	if path := os.Getenv(envName); path != "" {
+		return DevOverride{Path: path, BuildCommand: os.Getenv(envName + "_BUILD")}, true
+	}
+	override, ok = devFileOverrides[toolName]
+	ok = ok && override.Path != ""
+	return
+}
+
+// GetDevToolOverrideForVersion looks up an override for toolName@version first,
+// so a single version can be shadowed while the others still use real downloads.
+func GetDevToolOverrideForVersion(toolName, version string) (DevOverride, bool) {
+	if version != "" {
+		if override, ok := GetDevToolOverride(toolName + "@" + version); ok {
+			return override, true
+		}
+	}
+	return GetDevToolOverride(toolName)
+}
+
+func HasDevToolOverride(toolName string) bool {
+	_, ok := GetDevToolOverride(toolName)
+	return ok
+}
+
+// DevTool 指向开发者本地构建的工具，不会下载或安装
+type DevTool struct {
+	toolName     string
+	version      string
+	path         string
+	buildCommand string
+	watchDir     string
+	conf         *config.ToolConfig
+}
+
+// NewDevTool creates a tool for a local override. When the override path is a
+// folder and the tool is configured, the configured pathToEntry is used inside it.
+func NewDevTool(toolName string, override DevOverride, conf *config.ToolConfig) *DevTool {
+	path := override.Path
+	watchDir := override.WatchDir
+	if watchDir == "" {
+		watchDir = filepath.Dir(path)
+	}
+
+	version := "dev"
+	if conf != nil {
+		version = conf.Version
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			if override.WatchDir == "" {
+				watchDir = path
+			}
+			if entry := expandEnvIfSet(conf.Expand(conf.PathToEntry.Value)); entry != "" {
+				path = filepath.Join(path, entry)
+			}
+		}
+	}
+	return &DevTool{
+		toolName:     toolName,
+		version:      version,
+		path:         path,
+		buildCommand: override.BuildCommand,
+		watchDir:     watchDir,
+		conf:         conf,
+	}
+}
+
+func (p *DevTool) DoesToolExist() bool {
+	_, err := os.Stat(p.path)
+	return err == nil
+}
+
+func (p *DevTool) Install() error {
+	return p.InstallContext(context.Background())
+}
+
+func (p *DevTool) InstallContext(ctx context.Context) error {
+	if err := p.rebuildIfChanged(ctx); err != nil {
+		return err
+	}
+	if !p.DoesToolExist() {
+		return fmt.Errorf("dev override for tool %s not found: %s", p.toolName, p.path)
+	}
+	return nil
+}
+
+func (p *DevTool) InstallFromArchive(path string) error {
+	return fmt.Errorf("tool %s uses a dev override and cannot be installed from %s", p.toolName, path)
+}
+
+// needsRebuild 判断 watchDir 下是否有比二进制更新的文件，隐藏目录会被跳过
+func (p *DevTool) needsRebuild() bool {
+	binaryInfo, err := os.Stat(p.path)
+	if err != nil {
+		return true
+	}
+	builtAt := binaryInfo.ModTime()
+	absPath, _ := filepath.Abs(p.path)
+
+	errChanged := errors.New("changed")
+	err = filepath.WalkDir(p.watchDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != p.watchDir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if abs, _ := filepath.Abs(path); abs == absPath {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(builtAt) {
+			return errChanged
+		}
+		return nil
+	})
+	return err == errChanged
+}
+
+func (p *DevTool) rebuildIfChanged(ctx context.Context) error {
+	if p.buildCommand == "" {
+		return nil
+	}
+
+	devBuildLock.Lock()
+	defer devBuildLock.Unlock()
+	if !p.needsRebuild() {
+		return nil
+	}
+
+	cmd := shellCommand(ctx, p.buildCommand)
+	cmd.Dir = p.watchDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to rebuild dev tool %s: %w\n%s", p.toolName, err, output)
+	}
+	return nil
+}
+
+func (p *DevTool) CreateExecuteCmd(args ...string) (cmd *exec.Cmd, err error) {
+	return p.CreateExecuteCmdContext(context.Background(), args...)
+}
+
+func (p *DevTool) CreateExecuteCmdContext(ctx context.Context, args ...string) (cmd *exec.Cmd, err error) {
+	if err = p.rebuildIfChanged(ctx); err != nil {
+		return
+	}
+	if !p.DoesToolExist() {
+		return nil, fmt.Errorf("dev override for tool %s not found: %s", p.toolName, p.path)
+	}
+	cmd = exec.CommandContext(ctx, p.path, args...)
+	if env := p.getToolEnv(); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Dir = resolveWorkingDir(p.conf, filepath.Dir(p.path))
+	return
+}
+
+// {toolFolder} of a dev override is the folder containing its binary
+func (p *DevTool) getToolEnv() []string {
+	return buildToolEnv(p.conf, filepath.Dir(p.path))
+}
+
+func (p *DevTool) CreateExecuteCmdWithOptions(opts ExecOptions, args ...string) (cmd *exec.Cmd, err error) {
+	return p.createExecuteCmdWithOptions(context.Background(), opts, args...)
+}
+
+func (p *DevTool) createExecuteCmdWithOptions(ctx context.Context, opts ExecOptions, args ...string) (cmd *exec.Cmd, err error) {
+	cmd, err = p.CreateExecuteCmdContext(ctx, args...)
+	if err != nil {
+		return
+	}
+	var envWhitelist []string
+	if p.conf != nil {
+		envWhitelist = p.conf.EnvWhitelist
+	}
+	applyExecOptions(cmd, opts, envWhitelist, p.getToolEnv())
+	applyExecDir(cmd, opts, filepath.Dir(p.path))
+	return
+}
+
+func (p *DevTool) Execute(args ...string) (err error) {
+	return p.executeWithOptions(context.Background(), ExecOptions{}, args...)
+}
+
+func (p *DevTool) ExecuteContext(ctx context.Context, args ...string) (err error) {
+	return p.executeWithOptions(ctx, ExecOptions{}, args...)
+}
+
+// ExecuteWithTimeout runs the tool like ExecuteContext with a deadline of d,
+// a *TimeoutError is returned when it is exceeded.
+func (p *DevTool) ExecuteWithTimeout(d time.Duration, args ...string) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return p.executeWithOptions(ctx, ExecOptions{}, args...)
+}
+
+// ExecuteStream starts the tool and returns its output as streams, see ExecStream.
+func (p *DevTool) ExecuteStream(args ...string) (stream *ExecStream, err error) {
+	return p.executeStream(context.Background(), ExecOptions{}, args...)
+}
+
+// ExecuteStreamContext is ExecuteStream with options, ctx stops the tool like ExecuteContext.
+func (p *DevTool) ExecuteStreamContext(ctx context.Context, opts ExecOptions, args ...string) (stream *ExecStream, err error) {
+	return p.executeStream(ctx, opts, args...)
+}
+
+// ExecuteStreamFunc runs the tool and passes every line of its output to
+// onStdout and onStderr, either can be nil.
+func (p *DevTool) ExecuteStreamFunc(onStdout, onStderr OutputHandler, args ...string) (exitCode int, err error) {
+	stream, err := p.executeStream(context.Background(), ExecOptions{}, args...)
+	if err != nil {
+		return exitCodeOf(err), err
+	}
+	return consumeStream(stream, onStdout, onStderr)
+}
+
+func (p *DevTool) executeStream(ctx context.Context, opts ExecOptions, args ...string) (stream *ExecStream, err error) {
+	if err = p.rebuildIfChanged(ctx); err != nil {
+		return
+	}
+	cmd, err := p.createExecuteCmdWithOptions(context.Background(), opts, args...)
+	if err != nil {
+		return
+	}
+	return startStream(ctx, cmd, filepath.Dir(p.path), p.toolName, p.version, args)
+}
+
+func (p *DevTool) ExecuteWithOptions(opts ExecOptions, args ...string) (err error) {
+	return p.executeWithOptions(context.Background(), opts, args...)
+}
+
+func (p *DevTool) executeWithOptions(ctx context.Context, opts ExecOptions, args ...string) (err error) {
+	if err = p.rebuildIfChanged(ctx); err != nil {
+		return
+	}
+	cmd, err := p.createExecuteCmdWithOptions(context.Background(), opts, args...)
+	if err != nil {
+		return
+	}
+
+	startedAt := time.Now()
+	err = runTracked(ctx, cmd, filepath.Dir(p.path))
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = &TimeoutError{ToolName: p.toolName, Version: p.version}
+	}
+	statsStore.record(p.toolName, p.version, args, startedAt, err)
+	return
+}
+
+func (p *DevTool) GetVersion() string {
+	return p.version
+}
+
+func (p *DevTool) GetToolPath() string {
+	return p.path
+}
+
+func (p *DevTool) IsExecutable() bool {
+	return p.conf == nil || p.conf.IsExecutable()
+}
+
+func (p *DevTool) GetConfiguredEntry() string {
+	if p.conf == nil {
+		return ""
+	}
+	return expandEnvIfSet(p.conf.Expand(p.conf.PathToEntry.Value))
+}
+
+func (p *DevTool) GetDownloadURLForPlatform(goos, goarch string) (string, error) {
+	if p.conf == nil {
+		return "", fmt.Errorf("tool %s is not configured", p.toolName)
+	}
+	url, err := p.conf.DownloadURL.ValueFor(goos, goarch)
+	return p.conf.ExpandFor(url, goos, goarch), err
+}
+
+func (p *DevTool) GetMetadata() ToolMetadata {
+	if p.conf == nil {
+		return ToolMetadata{
+			Name:         p.toolName,
+			Version:      p.version,
+			IsExecutable: true,
+		}
+	}
+	return newToolMetadata(p.conf)
+}
+
+func (p *DevTool) GetInstallRecord() (*InstallRecord, error) {
+	return nil, fmt.Errorf("tool %s uses a dev override and is not installed", p.toolName)
+}
@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	DoctorOK      = "ok"
+	DoctorWarning = "warning"
+	DoctorError   = "error"
+)
+
+const (
+	// 剩余空间低于此值时给出警告
+	lowDiskSpace = 1024 * 1024 * 1024
+	// 比这更新的 .tmp_ 目录可能属于正在进行的安装，修复时不删除
+	leftoverMinAge       = time.Hour
+	doctorNetworkTimeout = 10 * time.Second
+)
+
+type DoctorCheck struct {
+	// e.g. "writable", "exec", "disk", "leftovers" or "network"
+	Name string
+	// the folder or tool the check is about
+	Target     string
+	Status     string
+	Message    string
+	Suggestion string
+}
+
+type DoctorOptions struct {
+	SkipNetwork bool
+	// Fix removes the leftovers of interrupted installs and removals.
+	Fix bool
+}
+
+// Doctor checks the environment tools are installed into and run from: that
+// the install folders are writable and allow executing files, the free disk
+// space, leftovers of interrupted installs and whether the download urls can
+// be reached. Every problem comes with a suggestion how to fix it.
+func (p *API) Doctor(ctx context.Context, opts DoctorOptions) (checks []DoctorCheck) {
+	for _, folder := range p.installRoots() {
+		checks = append(checks, checkFolderWritable(folder))
+		checks = append(checks, checkFolderExecutable(folder))
+		checks = append(checks, checkDiskSpace(folder))
+		checks = append(checks, checkLeftovers(folder, opts.Fix))
+	}
+	checks = append(checks, checkFolderWritable(GetTmpFolder()))
+	switch {
+	case opts.SkipNetwork:
+	case IsOfflineMode():
+		checks = append(checks, DoctorCheck{
+			Name: "network", Target: "downloads", Status: DoctorWarning,
+			Message:    "offline mode is enabled, the download urls are not checked",
+			Suggestion: "only installed versions can be used, turn offline mode off to install new ones",
+		})
+	default:
+		checks = append(checks, p.checkDownloadURLs(ctx)...)
+	}
+	return
+}
+
+// 配置中的工具用到的所有根目录，包括默认根目录
+func (p *API) installRoots() (roots []string) {
+	seen := map[string]bool{GetToolFolder(): true}
+	roots = append(roots, GetToolFolder())
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	for _, toolConfig := range p.config.ToolConfigs {
+		root := NewBaseTool(toolConfig).getRootFolder()
+		if !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+	sort.Strings(roots[1:])
+	return
+}
+
+func checkFolderWritable(folder string) DoctorCheck {
+	check := DoctorCheck{Name: "writable", Target: folder, Status: DoctorOK, Message: "folder is writable"}
+	if err := checkScopeWritable(ScopeDefault, folder); err != nil {
+		check.Status = DoctorError
+		check.Message = err.Error()
+		check.Suggestion = "grant write permission on the folder, or install into another folder with -root or another scope"
+	}
+	return check
+}
+
+// checkFolderExecutable 在 folder 中写入一个脚本并执行，检测 noexec 挂载
+func checkFolderExecutable(folder string) DoctorCheck {
+	check := DoctorCheck{Name: "exec", Target: folder, Status: DoctorOK, Message: "files in the folder can be executed"}
+	if runtime.GOOS == "windows" {
+		check.Message = "not checked on windows"
+		return check
+	}
+	f, err := os.CreateTemp(folder, ".exec_test_")
+	if err != nil {
+		check.Status = DoctorWarning
+		check.Message = fmt.Sprintf("could not write a test file: %v", err)
+		return check
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("#!/bin/sh\nexit 0\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0755)
+	}
+	if err == nil {
+		err = exec.Command(f.Name()).Run()
+	}
+	if err != nil {
+		check.Status = DoctorError
+		check.Message = fmt.Sprintf("could not execute a file in the folder: %v", err)
+		if errors.Is(err, syscall.EACCES) || os.IsPermission(err) {
+			check.Suggestion = "the file system is probably mounted with noexec, remount it without noexec or install into another folder with -root"
+		}
+	}
+	return check
+}
+
+func checkDiskSpace(folder string) DoctorCheck {
+	check := DoctorCheck{Name: "disk", Target: folder, Status: DoctorOK}
+	// the folder may not exist yet
+	path := folder
+	for {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+	free, err := diskFree(path)
+	if err != nil {
+		check.Status = DoctorWarning
+		check.Message = fmt.Sprintf("could not get the free disk space: %v", err)
+		return check
+	}
+	check.Message = fmt.Sprintf("%d MiB free", free/1024/1024)
+	if free < lowDiskSpace {
+		check.Status = DoctorWarning
+		check.Suggestion = "free some disk space, e.g. by removing old versions with remotetools uninstall"
+	}
+	return check
+}
+
+// checkLeftovers 查找 <root>/<os>/<arch>/<tool>/ 下中断的安装（.tmp_）和删除（.trash-）留下的目录
+func checkLeftovers(root string, fix bool) DoctorCheck {
+	check := DoctorCheck{Name: "leftovers", Target: root, Status: DoctorOK, Message: "no leftovers of interrupted installs"}
+	leftovers, _ := filepath.Glob(filepath.Join(root, "*", "*", "*", ".tmp_*"))
+	trash, _ := filepath.Glob(filepath.Join(root, "*", "*", "*", ".trash-*"))
+	leftovers = append(leftovers, trash...)
+	if len(leftovers) == 0 {
+		return check
+	}
+
+	var remaining []string
+	for _, leftover := range leftovers {
+		if fix && isStaleLeftover(leftover) && os.RemoveAll(leftover) == nil {
+			continue
+		}
+		remaining = append(remaining, leftover)
+	}
+	if len(remaining) == 0 {
+		check.Message = fmt.Sprintf("removed %d leftovers of interrupted installs", len(leftovers))
+		return check
+	}
+	check.Status = DoctorWarning
+	check.Message = fmt.Sprintf("%d leftovers of interrupted installs: %s", len(remaining), strings.Join(remaining, ", "))
+	check.Suggestion = fmt.Sprintf("run doctor with --fix to remove them, .tmp_ folders newer than %s are kept as they may belong to a running install", leftoverMinAge)
+	return check
+}
+
+func isStaleLeftover(path string) bool {
+	if strings.HasPrefix(filepath.Base(path), ".trash-") {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > leftoverMinAge
+}
+
+// checkDownloadURLs 对每个工具默认版本的每个下载地址请求第一个字节
+func (p *API) checkDownloadURLs(ctx context.Context) (checks []DoctorCheck) {
+	for _, toolName := range p.ToolNames() {
+		tool, err := p.GetTool(toolName)
+		downloaded, ok := tool.(*DownloadedTool)
+		if err != nil || !ok || downloaded.Source == SourceGit {
+			continue
+		}
+		target := toolName + " " + downloaded.GetVersion()
+		requests, err := downloaded.resolveDownloads(ctx)
+		if err != nil {
+			checks = append(checks, DoctorCheck{
+				Name: "network", Target: target, Status: DoctorError,
+				Message:    err.Error(),
+				Suggestion: "check the download config of the tool, and the credentials it needs",
+			})
+			continue
+		}
+		for _, request := range requests {
+			check := DoctorCheck{Name: "network", Target: target, Status: DoctorOK, Message: request.source() + " is reachable"}
+			if err := probeDownload(ctx, request); err != nil {
+				check.Status = DoctorError
+				check.Message = err.Error()
+				check.Suggestion = "check the network, the proxy (-proxy or HTTPS_PROXY) and that the url exists"
+			}
+			checks = append(checks, check)
+		}
+	}
+	return
+}
+
+func probeDownload(ctx context.Context, request downloadRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, doctorNetworkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, request.URL, nil)
+	if err != nil {
+		return withDisplayURL(err, request.source())
+	}
+	for name, values := range request.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := doDownload(req)
+	if err != nil {
+		return withDisplayURL(err, request.source())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("%s, url: %s", resp.Status, request.source())
+	}
+	return nil
+}
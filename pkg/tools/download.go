@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// 服务器在此时间内没有返回响应头则视为失败，以便尝试下一个镜像
+const downloadResponseTimeout = 30 * time.Second
+
+var downloadClient = &http.Client{
+	Transport:     newDownloadTransport(),
+	CheckRedirect: checkDownloadRedirect,
+}
+
+func newDownloadTransport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = downloadResponseTimeout
+	transport.Proxy = downloadProxy
+	return offlineTransport{next: transport}
+}
+
+// checkDownloadRedirect 只允许重定向到 http(s) 地址，服务器不能借重定向读取本地文件
+func checkDownloadRedirect(req *http.Request, via []*http.Request) error {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("refusing redirect to %s, only http and https are allowed", req.URL.Redacted())
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// doDownload 发送下载请求，file:// 地址直接读取本地文件，不经过 downloadClient
+func doDownload(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "file" {
+		return fileTransport{}.RoundTrip(req)
+	}
+	return downloadClient.Do(req)
+}
+
+// checkRemoteURL 检查由服务器返回的地址（release 资产、token realm 等），
+// 这些地址只能是 http(s)，file:// 只允许出现在配置中
+func checkRemoteURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("refusing url %s from server, only http and https are allowed", u.Redacted())
+	}
+	return nil
+}
+
+var (
+	proxyLock sync.RWMutex
+	proxyURL  *url.URL
+)
+
+// SetProxy sets the proxy used by all downloads, as http://, https:// or
+// socks5:// URL. It is used for every request, NO_PROXY is only honored for the
+// proxies from the environment (HTTP_PROXY/HTTPS_PROXY), which are used when
+// rawURL is empty.
+func SetProxy(rawURL string) error {
+	var parsed *url.URL
+	if rawURL != "" {
+		var err error
+		if parsed, err = url.Parse(rawURL); err != nil {
+			return fmt.Errorf("invalid proxy %s: %w", rawURL, err)
+		}
+		switch parsed.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", parsed.Scheme)
+		}
+	}
+
+	proxyLock.Lock()
+	defer proxyLock.Unlock()
+	proxyURL = parsed
+	return nil
+}
+
+// SetProxy sets the proxy used by all downloads, see the package level SetProxy.
+func (p *API) SetProxy(rawURL string) error {
+	return SetProxy(rawURL)
+}
+
+func downloadProxy(req *http.Request) (*url.URL, error) {
+	proxyLock.RLock()
+	proxy := proxyURL
+	proxyLock.RUnlock()
+	if proxy != nil {
+		return proxy, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// openDownload 发起 GET 请求，状态码不是 200 时返回错误。调用方负责关闭 Body
+func openDownload(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	return openDownloadAs(ctx, url, url, header)
+}
+
+// openDownloadAs 与 openDownload 相同，但错误中使用 displayURL。downloadURL 中展开的环境变量
+// 可能含有凭据，displayURL 是展开前的地址
+func openDownloadAs(ctx context.Context, downloadURL, displayURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err == nil {
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		var resp *http.Response
+		if resp, err = doDownload(req); err == nil {
+			if resp.StatusCode == http.StatusOK {
+				return resp, nil
+			}
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s, url: %s", resp.Status, displayURL)
+		}
+	}
+	if downloadURL != displayURL {
+		err = withDisplayURL(err, displayURL)
+	}
+	return nil, err
+}
+
+// withDisplayURL 把 err 中 *url.Error 的地址替换为 displayURL
+func withDisplayURL(err error, displayURL string) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = displayURL
+	}
+	return err
+}
+
+// openConfiguredDownload 下载配置中的地址 rawURL，其中的 ${NAME} 在请求前才展开
+func openConfiguredDownload(ctx context.Context, rawURL string, header http.Header) (*http.Response, error) {
+	downloadURL, err := config.ExpandEnv(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w, url: %s", err, rawURL)
+	}
+	return openDownloadAs(ctx, downloadURL, rawURL, header)
+}
+
+// openRemoteDownload 与 openDownload 相同，但只接受 http(s) 地址，用于服务器返回的地址
+func openRemoteDownload(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	if err := checkRemoteURL(url); err != nil {
+		return nil, err
+	}
+	return openDownload(ctx, url, header)
+}
+
+// expandHeaders 把配置中的 headers 转为 http.Header，值中的 ${NAME} 由 config.ExpandEnv
+// 替换为环境变量，引用的环境变量未设置时返回错误，避免带着空的凭据去请求
+func expandHeaders(headers map[string]string) (header http.Header, err error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	header = make(http.Header)
+	for name, value := range headers {
+		expanded, err := config.ExpandEnv(value)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", name, err)
+		}
+		header.Set(name, expanded)
+	}
+	return
+}
@@ -3,10 +3,9 @@ package tools
 import (
 	"archive/tar"
 	"archive/zip"
-	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
@@ -15,90 +14,478 @@ import (
 	"strings"
 
 	"github.com/kira1928/remotetools/pkg/config"
+	"github.com/kira1928/remotetools/pkg/tools/verify"
 )
 
 type DownloadedTool struct {
 	*BaseTool
+	// version is "latest" of a github source, see initLatest
+	followsLatest bool
+	// the latest release once resolved by resolveLatest
+	latestRelease *githubRelease
 }
 
 func NewDownloadTool(conf *config.ToolConfig) *DownloadedTool {
-	return &DownloadedTool{
+	tool := &DownloadedTool{
 		BaseTool: NewBaseTool(conf),
 	}
+	if conf.Source == SourceGitHub && conf.Version == LatestVersion {
+		tool.initLatest()
+	}
+	return tool
 }
 
 func (p *DownloadedTool) Install() error {
 	return p.DownloadTool()
 }
 
-func (p *DownloadedTool) getDownloadUrl() string {
-	return p.DownloadURL.Value
+// InstallContext installs the tool, cancelling ctx aborts the download.
+func (p *DownloadedTool) InstallContext(ctx context.Context) error {
+	return p.DownloadToolWithProgress(ctx, nil)
+}
+
+// InstallWithProgress installs the tool, reporting progress to callback (may be
+// nil). Cancelling ctx aborts the download.
+func (p *DownloadedTool) InstallWithProgress(ctx context.Context, callback ProgressCallback) error {
+	return p.DownloadToolWithProgress(ctx, callback)
+}
+
+func (p *DownloadedTool) getDownloadUrls() (urls []string) {
+	for _, url := range p.DownloadURL.Values {
+		if url != "" {
+			urls = append(urls, p.Expand(url))
+		}
+	}
+	return
+}
+
+// 工具的来源，对应配置项 source
+const (
+	SourceURL    = "url"
+	SourceGitHub = "github"
+	SourceGit    = "git"
+)
+
+// downloadRequest 是一个可以下载安装包的地址，由 resolveDownloads 按 source 解析得到
+type downloadRequest struct {
+	URL string
+	// file name of the artifact, taken from URL when empty
+	FileName string
+	Header   http.Header
+	// expected sha256 of the download, e.g. the digest of an OCI layer
+	SHA256 string
+	// URL as configured, with ${NAME} not expanded. It is recorded and shown
+	// instead of URL, which may contain credentials from the environment
+	Source string
+}
+
+func (r downloadRequest) source() string {
+	if r.Source != "" {
+		return r.Source
+	}
+	return r.URL
+}
+
+// resolveDownloads returns the artifacts to try in order.
+func (p *DownloadedTool) resolveDownloads(ctx context.Context) (requests []downloadRequest, err error) {
+	switch p.Source {
+	case "", SourceURL:
+		header, err := expandHeaders(p.Headers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download tool %s: %w", p.ToolName, err)
+		}
+		for _, rawURL := range p.getDownloadUrls() {
+			url, err := config.ExpandEnv(rawURL)
+			if err != nil {
+				return nil, fmt.Errorf("failed to download tool %s: %w, url: %s", p.ToolName, err, rawURL)
+			}
+			if strings.HasPrefix(url, OCIScheme) {
+				request, err := p.resolveOCIArtifact(ctx, url)
+				if err != nil {
+					return nil, fmt.Errorf("failed to download tool %s: %w", p.ToolName, err)
+				}
+				request.Source = rawURL
+				requests = append(requests, request)
+				continue
+			}
+			if strings.HasPrefix(url, S3Scheme) {
+				request, err := resolveS3Object(url)
+				if err != nil {
+					return nil, fmt.Errorf("failed to download tool %s: %w", p.ToolName, err)
+				}
+				request.Source = rawURL
+				requests = append(requests, request)
+				continue
+			}
+			requests = append(requests, downloadRequest{URL: url, Header: header, Source: rawURL})
+		}
+		if len(requests) == 0 {
+			return nil, fmt.Errorf("no download url configured for tool %s", p.ToolName)
+		}
+		return requests, nil
+	case SourceGitHub:
+		request, err := p.resolveGitHubAsset(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []downloadRequest{request}, nil
+	default:
+		return nil, fmt.Errorf("unknown source %q of tool %s", p.Source, p.ToolName)
+	}
 }
 
 func (p *DownloadedTool) DownloadTool() error {
+	return p.DownloadToolWithProgress(context.Background(), nil)
+}
+
+// DownloadToolWithProgress downloads and installs the tool. When downloadUrl
+// lists several mirrors, they are tried in order until one of them succeeds.
+func (p *DownloadedTool) DownloadToolWithProgress(ctx context.Context, callback ProgressCallback) error {
+	if p.followsLatest {
+		if err := p.resolveLatest(ctx); err != nil {
+			return err
+		}
+	}
+
 	// check if file already exists
 	if p.DoesToolExist() {
 		return nil
 	}
 
-	url := p.getDownloadUrl()
+	if err := p.checkWritable(); err != nil {
+		return err
+	}
+	return p.reinstall(ctx, callback)
+}
+
+// reinstall 不检查工具是否已安装，下载并安装到工具目录，已有的目录会被替换
+func (p *DownloadedTool) reinstall(ctx context.Context, callback ProgressCallback) error {
+	if _, err := config.ExpandEnv(p.PathToEntry.Value); err != nil {
+		return fmt.Errorf("invalid pathToEntry of tool %s: %w", p.ToolName, err)
+	}
+	if p.Source == SourceGit {
+		return p.installFromGit(ctx, callback)
+	}
+	return p.fetchArtifact(ctx, callback, func(filePath, source string, progress DownloadProgress) error {
+		return p.installFromFile(ctx, filePath, source, progress, callback)
+	})
+}
+
+// fetchArtifact 依次尝试各个下载地址，下载成功后交给 use 处理下载到 tmp 目录中的安装包
+func (p *DownloadedTool) fetchArtifact(ctx context.Context, callback ProgressCallback, use func(filePath, source string, progress DownloadProgress) error) error {
+	requests, err := p.resolveDownloads(ctx)
+	if err != nil {
+		return err
+	}
 
-	// download tool using the obtained URL
-	resp, err := http.Get(url)
+	tmpDir, err := p.makeTmpDir()
 	if err != nil {
 		return err
 	}
+	defer os.RemoveAll(tmpDir)
+
+	var failures []string
+	for _, request := range requests {
+		filePath, progress, err := p.download(ctx, request, tmpDir, callback)
+		if err == nil {
+			return use(filePath, request.source(), progress)
+		}
+		if ctx.Err() != nil || len(requests) == 1 {
+			return err
+		}
+		failures = append(failures, err.Error())
+	}
+	return fmt.Errorf("all %d download urls of tool %s failed: %s", len(requests), p.ToolName, strings.Join(failures, "; "))
+}
+
+// download 把 request 下载到 tmpDir 中，文件名没有指定时取自 url
+func (p *DownloadedTool) download(ctx context.Context, request downloadRequest, tmpDir string, callback ProgressCallback) (filePath string, progress DownloadProgress, err error) {
+	source := request.source()
+	resp, err := openDownloadAs(ctx, request.URL, source, request.Header)
+	if err != nil {
+		err = fmt.Errorf("failed to download tool %s: %w", p.ToolName, err)
+		return
+	}
 	defer resp.Body.Close()
 
-	// check if the response status code is 200
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download tool %s: %s, url: %s", p.ToolName, resp.Status, url)
+	downloadFileName := request.FileName
+	if downloadFileName == "" {
+		// get the file name from the URL
+		if downloadFileName, err = getFileNameFromURL(request.URL); err != nil {
+			return
+		}
 	}
 
-	// get the file name from the URL
-	downloadFileName, err := getFileNameFromURL(url)
+	progress = DownloadProgress{
+		ToolName:   p.ToolName,
+		Version:    p.Version,
+		Phase:      PhaseDownloading,
+		URL:        source,
+		TotalBytes: resp.ContentLength,
+	}
+	filePath = filepath.Join(tmpDir, downloadFileName)
+	body := limitDownloadRate(ctx, resp.Body, p.DownloadRateLimit)
+	progress, err = writeDownload(body, filePath, progress, callback)
 	if err != nil {
+		err = fmt.Errorf("failed to download tool %s: %w, url: %s", p.ToolName, err, source)
+		return
+	}
+	if request.SHA256 != "" {
+		if err = verifySHA256(filePath, request.SHA256); err != nil {
+			err = fmt.Errorf("failed to verify tool %s: %w", p.ToolName, err)
+		}
+	}
+	return
+}
+
+// InstallFromReader installs the tool from an artifact read from r, e.g. stdin
+// or an object store. filename decides the archive format and is the name
+// looked up in the checksum file, if one is configured.
+func (p *DownloadedTool) InstallFromReader(r io.Reader, filename string) error {
+	if p.DoesToolExist() {
+		return nil
+	}
+
+	if err := p.checkWritable(); err != nil {
 		return err
 	}
 
-	toolFolder := p.GetToolFolder()
+	progress := DownloadProgress{
+		ToolName:   p.ToolName,
+		Version:    p.Version,
+		Phase:      PhaseDownloading,
+		TotalBytes: -1,
+	}
+	return p.installFromReader(context.Background(), r, filepath.Base(filename), "reader", progress, nil)
+}
 
-	// Create the directory if it does not exist
-	if _, err := os.Stat(toolFolder); os.IsNotExist(err) {
-		err = os.MkdirAll(toolFolder, 0755) // You can adjust the file permission as needed
-		if err != nil {
-			return err
-		}
+func (p *DownloadedTool) InstallFromArchive(path string) error {
+	if p.DoesToolExist() {
+		return nil
+	}
+
+	if err := p.checkWritable(); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
 	}
 
-	tmpPath := filepath.Join(toolFolder, downloadFileName)
-	out, err := os.Create(tmpPath)
+	progress := DownloadProgress{
+		ToolName:        p.ToolName,
+		Version:         p.Version,
+		Phase:           PhaseVerifying,
+		DownloadedBytes: info.Size(),
+		TotalBytes:      info.Size(),
+	}
+	source, err := filepath.Abs(path)
+	if err != nil {
+		source = path
+	}
+	return p.installFromFile(context.Background(), path, source, progress, nil)
+}
+
+func (p *DownloadedTool) checkWritable() error {
+	scope := p.GetScope()
+	if scope == ScopeDefault {
+		return nil
+	}
+	scopeFolder, err := GetScopeFolder(scope)
 	if err != nil {
 		return err
 	}
+	return checkScopeWritable(scope, scopeFolder)
+}
+
+// installFromReader 把 r 写入 tmp 目录下的 fileName，再交给 installFromFile
+func (p *DownloadedTool) installFromReader(ctx context.Context, r io.Reader, fileName, source string, progress DownloadProgress, callback ProgressCallback) error {
+	tmpDir, err := p.makeTmpDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpPath := filepath.Join(tmpDir, fileName)
+	if progress, err = writeDownload(r, tmpPath, progress, callback); err != nil {
+		return err
+	}
+	return p.installFromFile(ctx, tmpPath, source, progress, callback)
+}
+
+// makeTmpDir creates a folder for one install in the tmp folder. Downloads go
+// there, only the extracted files go into the tool folder.
+func (p *DownloadedTool) makeTmpDir() (string, error) {
+	if err := os.MkdirAll(GetTmpFolder(), 0700); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(GetTmpFolder(), p.ToolName+"_")
+}
+
+func writeDownload(r io.Reader, path string, progress DownloadProgress, callback ProgressCallback) (DownloadProgress, error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return progress, err
+	}
+
+	body := newProgressReader(r, progress, callback)
 
 	// write the body to file
-	_, err = io.Copy(out, resp.Body)
+	_, err = io.Copy(out, body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return body.progress, err
+}
+
+// installFromFile 校验并解压一个完整的安装包，见 installInto。source 记录在安装记录中
+func (p *DownloadedTool) installFromFile(ctx context.Context, filePath, source string, progress DownloadProgress, callback ProgressCallback) error {
+	if err := p.verifyArtifact(ctx, filePath, progress, callback); err != nil {
+		return err
+	}
+
+	reportPhase(callback, progress, PhaseExtracting)
+	err := p.installInto(ctx, func(stagingFolder string) (*InstallRecord, error) {
+		if err := p.extractArtifact(filePath, stagingFolder); err != nil {
+			return nil, err
+		}
+		return newInstallRecord(source, filePath), nil
+	})
 	if err != nil {
-		out.Close()
 		return err
 	}
-	out.Close()
 
-	// 如果下载文件以 .zip 或 .tar.gz 结尾，则解压文件
-	if strings.HasSuffix(downloadFileName, ".zip") || strings.HasSuffix(downloadFileName, ".tar.gz") {
-		err = extractDownloadedFile(tmpPath)
+	reportPhase(callback, progress, PhaseCompleted)
+	return nil
+}
+
+// verifyArtifact 按配置的 sha256、checksumUrl 和签名校验安装包，并执行安装包扫描
+func (p *DownloadedTool) verifyArtifact(ctx context.Context, filePath string, progress DownloadProgress, callback ProgressCallback) error {
+	fileName := filepath.Base(filePath)
+	header, err := expandHeaders(p.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to install tool %s: %w", p.ToolName, err)
+	}
+
+	if expected := strings.TrimSpace(p.SHA256.Value); expected != "" {
+		reportPhase(callback, progress, PhaseVerifying)
+		if !isSHA256Hex(expected) {
+			return fmt.Errorf("invalid sha256 configured for tool %s: %s", p.ToolName, expected)
+		}
+		// a downloaded file is removed together with its tmp folder
+		if err := verifySHA256(filePath, expected); err != nil {
+			return fmt.Errorf("failed to verify tool %s: %w", p.ToolName, err)
+		}
+	} else if checksumURL := p.Expand(p.ChecksumURL.Value); checksumURL != "" {
+		reportPhase(callback, progress, PhaseVerifying)
+		expected, err := fetchChecksum(ctx, checksumURL, fileName, header)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to get checksum of tool %s: %w", p.ToolName, err)
+		}
+		if err = verifySHA256(filePath, expected); err != nil {
+			return fmt.Errorf("failed to verify tool %s: %w", p.ToolName, err)
+		}
+	}
+
+	if signatureURL := p.Expand(p.SignatureURL.Value); signatureURL != "" {
+		reportPhase(callback, progress, PhaseVerifying)
+		signature, err := fetchSmallFile(ctx, signatureURL, header)
+		if err != nil {
+			return fmt.Errorf("failed to download signature of tool %s: %w", p.ToolName, err)
 		}
+		if err = verify.File(p.SignatureType, p.PublicKey, filePath, signature); err != nil {
+			return fmt.Errorf("failed to verify signature of tool %s: %w", p.ToolName, err)
+		}
+	}
+
+	return scanArtifact(p.ToolName, filePath)
+}
+
+// extractArtifact 把归档解压到 dest，不是归档的安装包作为单个文件放入 dest
+func (p *DownloadedTool) extractArtifact(filePath, dest string) error {
+	if archiveFormat(filepath.Base(filePath)) != "" {
+		return extractArchive(filePath, dest, p.getExtractOptions())
+	}
+	return p.installSingleFile(filePath, dest)
+}
+
+// installInto 让 prepare 在工具目录旁的 .tmp_ 目录中准备好工具，完成后再整体改名为
+// 工具目录并执行 postInstall 命令，中途失败不会留下半个工具目录。
+// 之后写入 ManifestFile，prepare 返回的记录最后写入 CompleteMarkerFile
+func (p *DownloadedTool) installInto(ctx context.Context, prepare func(stagingFolder string) (*InstallRecord, error)) error {
+	toolFolder := filepath.FromSlash(p.GetToolFolder())
+	parentFolder := filepath.Dir(toolFolder)
+	if err := os.MkdirAll(parentFolder, 0755); err != nil {
+		return err
+	}
+	stagingFolder, err := os.MkdirTemp(parentFolder, ".tmp_"+p.Version+"_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingFolder)
+	// MkdirTemp 创建的目录只有所有者可以访问，改为与 MkdirAll 创建的目录一致
+	if err = os.Chmod(stagingFolder, 0755); err != nil {
+		return err
 	}
 
-	// delete the downloaded file
-	err = os.Remove(tmpPath)
+	record, err := prepare(stagingFolder)
 	if err != nil {
 		return err
 	}
+	if len(p.PostInstall) == 0 {
+		if err = writeManifest(stagingFolder, record); err != nil {
+			return err
+		}
+		if err = writeCompleteMarker(stagingFolder, record); err != nil {
+			return err
+		}
+	}
+
+	// an interrupted install may have left an incomplete tool folder behind
+	if err = os.RemoveAll(toolFolder); err != nil {
+		return err
+	}
+	if err = os.Rename(stagingFolder, toolFolder); err != nil {
+		return err
+	}
 
+	// 钩子执行完之前没有完成标记，工具不会被视为已安装
+	if len(p.PostInstall) > 0 {
+		if err = p.runHooks(ctx, "postInstall", p.PostInstall, toolFolder); err == nil {
+			err = writeManifest(toolFolder, record)
+		}
+		if err == nil {
+			err = writeCompleteMarker(toolFolder, record)
+		}
+		if err != nil {
+			if removeErr := removeToolFolder(toolFolder); removeErr != nil {
+				return fmt.Errorf("%w, and failed to roll back the install: %v", err, removeErr)
+			}
+			return err
+		}
+	}
+	// after postInstall, which may still change the files
+	dedupeToolFolder(toolFolder)
+	return nil
+}
+
+// runHooks 在工具目录中依次执行 postInstall/preUninstall 命令，任一失败即返回
+func (p *DownloadedTool) runHooks(ctx context.Context, hook string, commands []string, toolFolder string) error {
+	for _, command := range commands {
+		command = expandToolFolder(p.ToolConfig, command, toolFolder)
+		cmd := shellCommand(ctx, command)
+		cmd.Dir = toolFolder
+		if env := buildToolEnv(p.ToolConfig, toolFolder); len(env) > 0 {
+			cmd.Env = append(os.Environ(), env...)
+		}
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s command %q of tool %s failed: %w\n%s", hook, command, p.ToolName, err, output)
+		}
+	}
 	return nil
 }
 
@@ -115,77 +502,189 @@ func getFileNameFromURL(rawURL string) (string, error) {
 	return fileName, nil
 }
 
-func extractDownloadedFile(path string) error {
-	if strings.HasSuffix(path, ".zip") {
-		return extractZipFile(path, filepath.Dir(path))
-	} else if strings.HasSuffix(path, ".tar.gz") {
-		return extractTarGzFile(path)
-	} else {
+// installSingleFile 安装不是归档的下载文件（如直接发布的二进制），
+// 文件放在 pathToEntry 处，没有配置时使用下载的文件名
+func (p *DownloadedTool) installSingleFile(filePath, dest string) error {
+	entry, err := config.ExpandEnv(p.Expand(p.PathToEntry.Value))
+	if err != nil {
+		return fmt.Errorf("invalid pathToEntry of tool %s: %w", p.ToolName, err)
+	}
+	if entry == "" {
+		entry = filepath.Base(filePath)
+	}
+	target := filepath.Join(dest, entry)
+	if !isWithinDir(dest, target) || target == filepath.Clean(dest) {
+		return fmt.Errorf("invalid pathToEntry for tool %s: %s", p.ToolName, entry)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if p.IsExecutable() {
+		mode = 0755
+	}
+	if err := copyFile(filePath, target, mode); err != nil {
+		return err
+	}
+	// OpenFile 创建的文件权限受 umask 影响
+	return os.Chmod(target, mode)
+}
+
+// extractOptions 是从工具配置中取出的解压相关选项
+type extractOptions struct {
+	zipFilenameEncoding string
+	stripPrefix         int
+	extractSubdir       string
+}
+
+func (p *DownloadedTool) getExtractOptions() extractOptions {
+	return extractOptions{
+		zipFilenameEncoding: p.ZipFilenameEncoding,
+		stripPrefix:         p.StripPrefix,
+		extractSubdir:       p.ExtractSubdir,
+	}
+}
+
+// mapEntryName 按 stripPrefix 和 extractSubdir 转换归档条目名，
+// ok 为 false 时该条目不需要解压
+func (p extractOptions) mapEntryName(name string) (mapped string, ok bool) {
+	if p.stripPrefix == 0 && p.extractSubdir == "" {
+		return name, true
+	}
+	var parts []string
+	for _, part := range strings.Split(strings.ReplaceAll(name, "\\", "/"), "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) <= p.stripPrefix {
+		return "", false
+	}
+	parts = parts[p.stripPrefix:]
+	if p.extractSubdir != "" {
+		subdir := strings.Split(strings.Trim(path.Clean(strings.ReplaceAll(p.extractSubdir, "\\", "/")), "/"), "/")
+		if len(parts) <= len(subdir) {
+			return "", false
+		}
+		for i := range subdir {
+			if parts[i] != subdir[i] {
+				return "", false
+			}
+		}
+		parts = parts[len(subdir):]
+	}
+	return strings.Join(parts, "/"), true
+}
+
+func (p extractOptions) validate() error {
+	if p.stripPrefix < 0 {
+		return fmt.Errorf("invalid stripPrefix: %d", p.stripPrefix)
+	}
+	if p.extractSubdir != "" {
+		subdir := path.Clean(strings.ReplaceAll(p.extractSubdir, "\\", "/"))
+		if subdir == "." || subdir == "/" || subdir == ".." || strings.HasPrefix(subdir, "../") {
+			return fmt.Errorf("invalid extractSubdir: %s", p.extractSubdir)
+		}
+	}
+	return nil
+}
+
+// extractArchive 根据文件名选择解压方式，所有支持的格式见 archiveFormat
+func extractArchive(path string, dest string, opts extractOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	switch format := archiveFormat(path); format {
+	case ArchiveZip:
+		return extractZipFile(path, dest, opts)
+	case ArchiveTarGz, ArchiveTarBz2, ArchiveTarZst:
+		return extractTarFile(path, format, dest, opts)
+	default:
 		return fmt.Errorf("unsupported file format: %s", path)
 	}
 }
 
 // 解压 zip 文件
-func extractZipFile(zipPath string, dest string) error {
+func extractZipFile(zipPath string, dest string, opts extractOptions) error {
 	r, err := zip.OpenReader(zipPath)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
 
-	for _, f := range r.File {
-		rc, err := f.Open()
+	names, err := decodeZipNames(r.File, opts.zipFilenameEncoding)
+	if err != nil {
+		return err
+	}
+
+	dest = toLongPathRoot(dest)
+	extracted := 0
+	for i, f := range r.File {
+		name, ok := opts.mapEntryName(names[i])
+		if !ok {
+			continue
+		}
+		extracted++
+
+		fpath, err := archiveEntryPath(dest, name)
 		if err != nil {
 			return err
 		}
-		defer rc.Close()
-
-		fpath := filepath.Join(dest, f.Name)
 		if f.FileInfo().IsDir() {
-			os.MkdirAll(fpath, os.ModePerm)
-		} else {
-			var dir string
-			if lastIndex := strings.LastIndex(fpath, string(os.PathSeparator)); lastIndex > -1 {
-				dir = fpath[:lastIndex]
-			}
-			err = os.MkdirAll(dir, os.ModePerm)
-			if err != nil {
-				log.Fatal(err)
-				return err
-			}
-			f, err := os.OpenFile(
-				fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				return err
-			}
-			defer f.Close()
-
-			_, err = io.Copy(f, rc)
-			if err != nil {
+			if err = os.MkdirAll(fpath, os.ModePerm); err != nil {
 				return err
 			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArchiveFile(fpath, f.Mode(), rc)
+		rc.Close()
+		if err != nil {
+			return err
 		}
 	}
-	return nil
+	return checkExtracted(extracted, opts)
 }
 
-func extractTarGzFile(path string) error {
-	// Open the tar.gz file for reading
-	file, err := os.Open(path)
+// writeArchiveFile 把归档中的一个文件写入 path，父目录不存在时先创建。
+// 文件在返回前关闭，解压大量文件时不会耗尽文件描述符
+func writeArchiveFile(path string, mode os.FileMode, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// 设置了 stripPrefix 或 extractSubdir 时，没有任何条目被解压通常是配置写错了
+func checkExtracted(extracted int, opts extractOptions) error {
+	if extracted > 0 || (opts.stripPrefix == 0 && opts.extractSubdir == "") {
+		return nil
+	}
+	return fmt.Errorf("no archive entries left after applying stripPrefix %d and extractSubdir %q", opts.stripPrefix, opts.extractSubdir)
+}
 
-	// Create a gzip reader
-	gzReader, err := gzip.NewReader(file)
+// 解压 tar 归档，format 决定外层的压缩格式
+func extractTarFile(path, format, dest string, opts extractOptions) error {
+	tarReader, closeArchive, err := openTarFile(path, format)
 	if err != nil {
 		return err
 	}
-	defer gzReader.Close()
+	defer closeArchive()
 
-	// Create a tar reader
-	tarReader := tar.NewReader(gzReader)
+	dest = toLongPathRoot(dest)
+	extracted := 0
 
 	// Extract each file from the tar archive
 	for {
@@ -197,8 +696,17 @@ func extractTarGzFile(path string) error {
 			return err
 		}
 
+		name, ok := opts.mapEntryName(header.Name)
+		if !ok {
+			continue
+		}
+		extracted++
+
 		// Determine the file path for the extracted file
-		targetPath := filepath.Join(filepath.Dir(path), header.Name)
+		targetPath, err := archiveEntryPath(dest, name)
+		if err != nil {
+			return err
+		}
 
 		// Check if the file is a directory
 		if header.FileInfo().IsDir() {
@@ -210,19 +718,91 @@ func extractTarGzFile(path string) error {
 			continue
 		}
 
-		// Create the file
-		file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode())
-		if err != nil {
-			return err
+		if header.Typeflag == tar.TypeLink {
+			linkName, ok := opts.mapEntryName(header.Linkname)
+			if !ok {
+				return fmt.Errorf("hard link target %s is not extracted", header.Linkname)
+			}
+			if err := extractTarHardLink(dest, targetPath, linkName); err != nil {
+				return err
+			}
+			continue
 		}
-		defer file.Close()
 
-		// Copy the contents of the file from the tar archive to the destination file
-		_, err = io.Copy(file, tarReader)
-		if err != nil {
+		// Create the file and copy its contents from the tar archive
+		if err = writeArchiveFile(targetPath, header.FileInfo().Mode(), tarReader); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return checkExtracted(extracted, opts)
+}
+
+// extractTarHardLink 在目标目录内重建硬链接，链接目标必须是已解压到 dest 内的普通文件；
+// 文件系统不支持硬链接时退化为复制
+func extractTarHardLink(dest, targetPath, linkName string) error {
+	linkTarget, err := archiveEntryPath(dest, linkName)
+	if err != nil || !isWithinDir(dest, targetPath) {
+		return fmt.Errorf("hard link %s -> %s points outside of %s", targetPath, linkName, dest)
+	}
+	info, err := os.Lstat(linkTarget)
+	if err != nil {
+		return fmt.Errorf("hard link target %s not found: %w", linkName, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("hard link target %s is not a regular file", linkName)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return err
+	}
+	os.Remove(targetPath)
+	if err = os.Link(linkTarget, targetPath); err == nil {
+		return nil
+	}
+	return copyFile(linkTarget, targetPath, info.Mode())
+}
+
+// archiveEntryPath 返回归档条目在 dest 中的路径。绝对路径、带盘符的路径和
+// 通过 .. 跳出 dest 的条目会被拒绝（Zip Slip），\ 也被当作分隔符处理
+func archiveEntryPath(dest, name string) (string, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(slashed, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" ||
+		(len(slashed) >= 2 && slashed[1] == ':') {
+		return "", fmt.Errorf("archive entry %s has an absolute path", name)
+	}
+	for _, element := range strings.Split(slashed, "/") {
+		if element == ".." {
+			return "", fmt.Errorf("archive entry %s points outside of the destination", name)
+		}
+	}
+
+	target := filepath.Join(dest, filepath.FromSlash(slashed))
+	if !isWithinDir(dest, target) {
+		return "", fmt.Errorf("archive entry %s points outside of the destination", name)
+	}
+	return target, nil
+}
+
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+type ExecOptions struct {
+	// IsolatedEnv starts the tool with only the whitelisted host variables
+	// (see IsolatedEnvWhitelist and the envWhitelist config field) plus Env.
+	IsolatedEnv bool
+	Env         map[string]string
+	// Dir overrides the workingDir config field, InToolFolder starts the tool
+	// from its install folder instead.
+	Dir          string
+	InToolFolder bool
+}
+
+// IsolatedEnvWhitelist 是隔离环境下默认保留的宿主环境变量
+var IsolatedEnvWhitelist = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_ALL", "TZ",
+	"TMPDIR", "TMP", "TEMP",
+	"SYSTEMROOT", "WINDIR", "COMSPEC", "PATHEXT", "USERPROFILE", "LOCALAPPDATA", "APPDATA", "PROGRAMDATA",
+}
+
+// 配置了 env 字段的工具在宿主环境（或隔离环境）之上加上 toolEnv，调用方的 ExecOptions.Env 优先
+func buildExecEnv(opts ExecOptions, toolWhitelist []string, toolEnv []string) []string {
+	var env []string
+	if opts.IsolatedEnv {
+		allowed := make(map[string]bool)
+		for _, name := range append(append([]string(nil), IsolatedEnvWhitelist...), toolWhitelist...) {
+			allowed[normalizeEnvName(name)] = true
+		}
+		for _, kv := range os.Environ() {
+			name, _, _ := strings.Cut(kv, "=")
+			if allowed[normalizeEnvName(name)] {
+				env = append(env, kv)
+			}
+		}
+	} else {
+		env = os.Environ()
+	}
+
+	env = append(env, toolEnv...)
+
+	names := make([]string, 0, len(opts.Env))
+	for name := range opts.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+opts.Env[name])
+	}
+	return env
+}
+
+// 环境变量名在 Windows 上不区分大小写
+func normalizeEnvName(name string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+func applyExecOptions(cmd *exec.Cmd, opts ExecOptions, toolWhitelist []string, toolEnv []string) {
+	if !opts.IsolatedEnv && len(opts.Env) == 0 {
+		return
+	}
+	cmd.Env = buildExecEnv(opts, toolWhitelist, toolEnv)
+}
+
+// resolveWorkingDir 返回配置的 workingDir，相对路径基于工具目录，未配置时返回空
+func resolveWorkingDir(conf *config.ToolConfig, toolFolder string) string {
+	if conf == nil || conf.WorkingDir == "" {
+		return ""
+	}
+	dir := filepath.FromSlash(expandToolFolder(conf, conf.WorkingDir, toolFolder))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(toolFolder, dir)
+	}
+	return dir
+}
+
+func applyExecDir(cmd *exec.Cmd, opts ExecOptions, toolFolder string) {
+	if opts.Dir != "" {
+		cmd.Dir = opts.Dir
+	} else if opts.InToolFolder {
+		cmd.Dir = toolFolder
+	}
+}
+
+// buildToolEnv returns the env field of conf as NAME=value, sorted by name.
+func buildToolEnv(conf *config.ToolConfig, toolFolder string) []string {
+	if conf == nil || len(conf.Env) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(conf.Env))
+	for name := range conf.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		env = append(env, name+"="+expandToolFolder(conf, conf.Env[name], toolFolder))
+	}
+	return env
+}
+
+// expandToolFolder 在 Expand 的基础上展开已设置的环境变量，并把 {toolFolder} 替换为工具目录
+func expandToolFolder(conf *config.ToolConfig, s, toolFolder string) string {
+	return strings.ReplaceAll(expandEnvIfSet(conf.Expand(s)), "{toolFolder}", toolFolder)
+}
+
+// shellCommand 通过系统 shell 执行 command，Windows 上使用 cmd /C
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxRecentExecutions = 20
+
+type ExecutionRecord struct {
+	Args      []string
+	ArgsHash  string
+	ExitCode  int
+	Duration  time.Duration
+	StartedAt time.Time
+	Error     string
+}
+
+type ExecutionStats struct {
+	ToolName      string
+	Version       string
+	Count         int
+	Failures      int
+	TotalDuration time.Duration
+	ExitCodes     map[int]int
+	Recent        []ExecutionRecord
+}
+
+// ArgsRedactor 在记录执行参数前对参数脱敏，返回 nil 表示不记录参数原文
+type ArgsRedactor func(args []string) []string
+
+type execStatsStore struct {
+	lock     sync.Mutex
+	stats    map[string]*ExecutionStats
+	redactor ArgsRedactor
+}
+
+var statsStore = &execStatsStore{
+	stats: make(map[string]*ExecutionStats),
+}
+
+func SetArgsRedactor(redactor ArgsRedactor) {
+	statsStore.lock.Lock()
+	defer statsStore.lock.Unlock()
+	statsStore.redactor = redactor
+}
+
+// HashArgs returns the hash stored with every execution record, so callers can
+// look up runs of a known invocation even when the args themselves are redacted.
+func HashArgs(args []string) string {
+	h := sha256.New()
+	for _, arg := range args {
+		h.Write([]byte(arg))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *execStatsStore) record(toolName, version string, args []string, startedAt time.Time, runErr error) {
+	record := ExecutionRecord{
+		ArgsHash:  HashArgs(args),
+		Duration:  time.Since(startedAt),
+		StartedAt: startedAt,
+	}
+	if runErr != nil {
+		record.Error = runErr.Error()
+		record.ExitCode = exitCodeOf(runErr)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.redactor != nil {
+		record.Args = s.redactor(append([]string(nil), args...))
+	} else {
+		record.Args = append([]string(nil), args...)
+	}
+
+	key := toolName + "@" + version
+	stats, ok := s.stats[key]
+	if !ok {
+		stats = &ExecutionStats{
+			ToolName:  toolName,
+			Version:   version,
+			ExitCodes: make(map[int]int),
+		}
+		s.stats[key] = stats
+	}
+	stats.Count++
+	if record.ExitCode != 0 {
+		stats.Failures++
+	}
+	stats.TotalDuration += record.Duration
+	stats.ExitCodes[record.ExitCode]++
+	stats.Recent = append(stats.Recent, record)
+	if len(stats.Recent) > maxRecentExecutions {
+		stats.Recent = stats.Recent[len(stats.Recent)-maxRecentExecutions:]
+	}
+}
+
+// exitCodeOf returns the exit code of a finished process, -1 when it did not
+// exit normally, e.g. it could not be started or was stopped.
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func (s *execStatsStore) get(toolName string) (result []ExecutionStats) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for key, stats := range s.stats {
+		if !strings.HasPrefix(key, toolName+"@") {
+			continue
+		}
+		copied := *stats
+		copied.ExitCodes = make(map[int]int, len(stats.ExitCodes))
+		for code, count := range stats.ExitCodes {
+			copied.ExitCodes[code] = count
+		}
+		copied.Recent = append([]ExecutionRecord(nil), stats.Recent...)
+		result = append(result, copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return
+}
+
+// GetExecutionStats returns the statistics of every recorded version of a tool.
+func (p *API) GetExecutionStats(toolName string) []ExecutionStats {
+	return statsStore.get(toolName)
+}
+
+func (p *API) ResetExecutionStats() {
+	statsStore.lock.Lock()
+	defer statsStore.lock.Unlock()
+	statsStore.stats = make(map[string]*ExecutionStats)
+}
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// 回调模式下单行输出的最大长度，遇到更长的行时之后的输出被丢弃
+const maxStreamLineSize = 1024 * 1024
+
+// ExecStream 是 ExecuteStream 启动的工具进程。Stdout 和 Stderr 都需要读完或关闭，
+// 关闭后剩余的输出被丢弃，否则进程会因管道写满而阻塞
+type ExecStream struct {
+	Stdout io.ReadCloser
+	Stderr io.ReadCloser
+	Pid    int
+
+	done     chan struct{}
+	exitCode int
+	err      error
+}
+
+// Wait waits for the process to exit and returns its exit code, -1 when it did
+// not exit normally, and the error as returned by Execute.
+func (s *ExecStream) Wait() (exitCode int, err error) {
+	<-s.done
+	return s.exitCode, s.err
+}
+
+// discardOnClose 在读端关闭后丢弃写入的数据，让进程的输出管道不会被写满
+type discardOnClose struct {
+	*io.PipeWriter
+}
+
+func (w discardOnClose) Write(b []byte) (int, error) {
+	if _, err := w.PipeWriter.Write(b); errors.Is(err, io.ErrClosedPipe) {
+		return len(b), nil
+	} else if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// startStream 启动 cmd 并把输出接到返回的 ExecStream 上，执行记录与 Execute 相同
+func startStream(ctx context.Context, cmd *exec.Cmd, toolFolder, toolName, version string, args []string) (*ExecStream, error) {
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	cmd.Stdout = discardOnClose{stdoutWriter}
+	cmd.Stderr = discardOnClose{stderrWriter}
+
+	startedAt := time.Now()
+	wait, err := startTracked(ctx, cmd, toolFolder)
+	if err != nil {
+		statsStore.record(toolName, version, args, startedAt, err)
+		return nil, err
+	}
+
+	stream := &ExecStream{
+		Stdout: stdoutReader,
+		Stderr: stderrReader,
+		Pid:    cmd.Process.Pid,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		err := wait()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = &TimeoutError{ToolName: toolName, Version: version}
+		}
+		statsStore.record(toolName, version, args, startedAt, err)
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		stream.exitCode, stream.err = exitCodeOf(err), err
+		close(stream.done)
+	}()
+	return stream, nil
+}
+
+// OutputHandler 接收工具输出的一行，不含换行符
+type OutputHandler func(line string)
+
+// consumeStream 把 stream 的输出逐行交给 onStdout、onStderr（可以为 nil），等待进程退出
+func consumeStream(stream *ExecStream, onStdout, onStderr OutputHandler) (exitCode int, err error) {
+	var wg sync.WaitGroup
+	for _, output := range []struct {
+		reader  io.ReadCloser
+		handler OutputHandler
+	}{
+		{stream.Stdout, onStdout},
+		{stream.Stderr, onStderr},
+	} {
+		wg.Add(1)
+		go func(reader io.ReadCloser, handler OutputHandler) {
+			defer wg.Done()
+			defer reader.Close()
+			if handler == nil {
+				return
+			}
+			scanner := bufio.NewScanner(reader)
+			scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+			for scanner.Scan() {
+				handler(scanner.Text())
+			}
+		}(output.reader, output.handler)
+	}
+	wg.Wait()
+	return stream.Wait()
+}
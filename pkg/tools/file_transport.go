@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// fileTransport 让 file:// 地址与 http 地址走同样的下载流程，可用于配置中的 downloadUrl、
+// checksumUrl 等地址，离线环境和测试时不经过 HTTP。它只由 doDownload 直接调用，
+// 没有注册到 downloadClient，HTTP 服务器的重定向不能指向本地文件；服务器返回的地址
+// 由 checkRemoteURL 拒绝 file://
+type fileTransport struct{}
+
+func (fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, err := fileURLPath(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	resp := &http.Response{
+		Proto:      "HTTP/1.0",
+		ProtoMajor: 1,
+		Header:     make(http.Header),
+		Request:    req,
+	}
+
+	f, err := os.Open(path)
+	if err == nil {
+		var info os.FileInfo
+		if info, err = f.Stat(); err == nil && info.IsDir() {
+			err = fmt.Errorf("%s is a directory", path)
+		}
+		if err != nil {
+			f.Close()
+		} else {
+			resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+			resp.ContentLength = info.Size()
+			resp.Body = f
+			return resp, nil
+		}
+	}
+	if os.IsNotExist(err) {
+		resp.StatusCode, resp.Status = http.StatusNotFound, "404 Not Found"
+		resp.Body = io.NopCloser(strings.NewReader(""))
+		return resp, nil
+	}
+	return nil, err
+}
+
+// fileURLPath 把 file:///path、file:///C:/path 和 Windows 上的 file://host/share 转为本地路径
+func fileURLPath(u *url.URL) (string, error) {
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		if u.Host != "" && u.Host != "localhost" {
+			return `\\` + u.Host + filepath.FromSlash(path), nil
+		}
+		// /C:/path
+		if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+			path = path[1:]
+		}
+		return filepath.FromSlash(path), nil
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("unsupported file url %s, expected file:///absolute/path", u)
+	}
+	return path, nil
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/kira1928/remotetools/pkg/tools/storage"
+)
+
+// defaultDataFolder 返回各平台约定的数据目录：
+// Linux 为 $XDG_DATA_HOME 或 ~/.local/share，macOS 为 ~/Library/Application Support，
+// Windows 为 %LOCALAPPDATA%
+func defaultDataFolder() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, "remotetools"), nil
+		}
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Application Support", "remotetools"), nil
+	default:
+		if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+			return filepath.Join(dir, "remotetools"), nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "remotetools"), nil
+}
+
+// defaultCacheFolder 返回各平台约定的缓存目录：
+// Linux 为 $XDG_CACHE_HOME 或 ~/.cache，macOS 为 ~/Library/Caches，
+// Windows 为 %LOCALAPPDATA%\remotetools\cache
+func defaultCacheFolder() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(dir, "remotetools", "cache"), nil
+	}
+	return filepath.Join(dir, "remotetools"), nil
+}
+
+// DirSize returns the size of the files in path. Hard linked files, e.g. by
+// the content store, are counted once.
+func DirSize(path string) (size int64, err error) {
+	// 有多个硬链接的文件，按大小分组以便用 os.SameFile 比较
+	linked := make(map[int64][]os.FileInfo)
+	err = filepath.WalkDir(path, func(filePath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if links, err := storage.LinkCount(filePath); err == nil && links > 1 {
+			for _, other := range linked[info.Size()] {
+				if os.SameFile(info, other) {
+					return nil
+				}
+			}
+			linked[info.Size()] = append(linked[info.Size()], info)
+		}
+		size += info.Size()
+		return nil
+	})
+	return
+}
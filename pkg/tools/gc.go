@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LastUsedFile 的修改时间是工具版本最近一次被执行的时间，GC 按它淘汰最久未使用的版本。
+// 没有这个文件时使用安装时间
+const LastUsedFile = ".last-used"
+
+// GCPolicy decides which installed versions GC removes. The default version of
+// a tool, pinned versions, versions other installed tools depend on and
+// versions that are running are always kept.
+type GCPolicy struct {
+	// remove the least recently used versions until the tool folder takes at
+	// most MaxSize bytes, 0 for no limit
+	MaxSize int64
+	// remove versions not used for longer than MaxAge, 0 for no limit
+	MaxAge time.Duration
+	// versions that are never removed, as "tool" or "tool@version"
+	Keep []string
+	// DryRun only reports what would be removed.
+	DryRun bool
+}
+
+type GCEntry struct {
+	ToolName string    `json:"tool"`
+	Version  string    `json:"version"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+type GCReport struct {
+	Removed []GCEntry `json:"removed"`
+	// size of the tool folder before and after the removal
+	SizeBefore int64 `json:"sizeBefore"`
+	SizeAfter  int64 `json:"sizeAfter"`
+}
+
+var (
+	gcLock      sync.Mutex
+	maxToolSize atomic.Int64
+)
+
+// SetMaxToolFolderSize limits the size of the tool folder, see GetToolFolder.
+// After every API.Install the least recently used versions are removed until
+// the folder fits again, see GC. 0 (default) disables the limit.
+func SetMaxToolFolderSize(size int64) {
+	maxToolSize.Store(size)
+}
+
+func GetMaxToolFolderSize() int64 {
+	return maxToolSize.Load()
+}
+
+// SetMaxToolFolderSize limits the size of the tool folder, see the package level SetMaxToolFolderSize.
+func (p *API) SetMaxToolFolderSize(size int64) {
+	SetMaxToolFolderSize(size)
+}
+
+// GC removes installed versions in the tool folder according to policy, the
+// least recently used first. Only versions of configured tools are removed,
+// each one like Uninstall. Versions that fail to be removed are logged and
+// skipped.
+func (p *API) GC(policy GCPolicy) (report GCReport, err error) {
+	gcLock.Lock()
+	defer gcLock.Unlock()
+
+	root := GetToolFolder()
+	if report.SizeBefore, err = DirSize(root); err != nil && !os.IsNotExist(err) {
+		return report, err
+	}
+	err = nil
+	report.SizeAfter = report.SizeBefore
+
+	candidates := p.gcCandidates(root, policy.Keep)
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].LastUsed.Before(candidates[j].LastUsed)
+	})
+	now := time.Now()
+	for _, candidate := range candidates {
+		expired := policy.MaxAge > 0 && now.Sub(candidate.LastUsed) > policy.MaxAge
+		oversized := policy.MaxSize > 0 && report.SizeAfter > policy.MaxSize
+		if !expired && !oversized {
+			// 按使用时间排序，后面的版本更新
+			break
+		}
+		if !policy.DryRun {
+			lock := lockInstall(candidate.ToolName + "@" + candidate.Version)
+			err := p.Uninstall(candidate.ToolName, candidate.Version)
+			lock.Unlock()
+			if err != nil {
+				log.Printf("failed to remove %s %s: %v\n", candidate.ToolName, candidate.Version, err)
+				continue
+			}
+		}
+		report.Removed = append(report.Removed, candidate)
+		report.SizeAfter -= candidate.Size
+	}
+	return
+}
+
+// gcCandidates 返回 root 中可以被删除的已安装版本
+func (p *API) gcCandidates(root string, keep []string) (candidates []GCEntry) {
+	for _, toolName := range p.ToolNames() {
+		if containsString(keep, toolName) {
+			continue
+		}
+		defaultVersion := ""
+		if tool, err := p.GetTool(toolName); err == nil && tool != nil {
+			defaultVersion = tool.GetVersion()
+		}
+		pinnedVersion, _ := p.GetPinnedVersion(toolName)
+
+		versions, _ := p.GetInstalledVersions(toolName)
+		for _, version := range versions {
+			if version == defaultVersion || version == pinnedVersion || containsString(keep, toolName+"@"+version) {
+				continue
+			}
+			tool, err := p.GetToolWithVersion(toolName, version)
+			if err != nil {
+				continue
+			}
+			downloaded, ok := tool.(*DownloadedTool)
+			if !ok || downloaded.getRootFolder() != root {
+				continue
+			}
+			toolFolder := filepath.FromSlash(downloaded.GetToolFolder())
+			if len(runningProcessesIn(toolFolder)) > 0 || len(p.GetDependents(toolName, version)) > 0 {
+				continue
+			}
+			size, _ := DirSize(toolFolder)
+			candidates = append(candidates, GCEntry{
+				ToolName: toolName,
+				Version:  version,
+				Size:     size,
+				LastUsed: lastUsedTime(toolFolder),
+			})
+		}
+	}
+	return
+}
+
+// enforceMaxSize 在安装后按 SetMaxToolFolderSize 的限制回收空间，刚安装的版本不会被删除
+func (p *API) enforceMaxSize(toolName, version string) {
+	maxSize := GetMaxToolFolderSize()
+	if maxSize <= 0 {
+		return
+	}
+	if _, err := p.GC(GCPolicy{MaxSize: maxSize, Keep: []string{toolName + "@" + version}}); err != nil {
+		log.Printf("failed to limit the size of the tool folder: %v\n", err)
+	}
+}
+
+// touchLastUsed 更新 LastUsedFile，只读的根目录等写入失败的情况直接忽略
+func touchLastUsed(toolFolder string) {
+	path := filepath.Join(toolFolder, LastUsedFile)
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); os.IsNotExist(err) {
+		if f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+			f.Close()
+		}
+	}
+}
+
+func lastUsedTime(toolFolder string) time.Time {
+	for _, name := range []string{LastUsedFile, CompleteMarkerFile} {
+		if info, err := os.Stat(filepath.Join(toolFolder, name)); err == nil {
+			return info.ModTime()
+		}
+	}
+	if info, err := os.Stat(toolFolder); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+// ParseSize parses a size such as "10GB", "512M" or "1048576". The units are
+// powers of 1024, B, K/KB/KiB, M/MB/MiB, G/GB/GiB and T/TB/TiB.
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "IB"), "B")
+	multiplier := int64(1)
+	if n := len(value); n > 0 {
+		if i := strings.IndexByte("KMGT", value[n-1]); i >= 0 {
+			multiplier = 1 << (10 * (i + 1))
+			value = value[:n-1]
+		}
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(number * float64(multiplier)), nil
+}
@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// installFromGit 浅克隆 repo 的 tag 到工具目录，再执行配置的构建命令。
+// .git 目录不会保留在工具目录中
+func (p *DownloadedTool) installFromGit(ctx context.Context, callback ProgressCallback) error {
+	if p.Repo == "" {
+		return fmt.Errorf("no repo configured for tool %s", p.ToolName)
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git is required to install tool %s: %w", p.ToolName, err)
+	}
+	tag := "v{version}"
+	if p.Tag != "" {
+		tag = p.Tag
+	}
+	tag = p.Expand(tag)
+	// repo 用于记录和提示，cloneURL 才展开 ${NAME}，其中可能含有凭据
+	repo := p.Expand(p.Repo)
+	cloneURL, err := config.ExpandEnv(repo)
+	if err != nil {
+		return fmt.Errorf("invalid repo of tool %s: %w", p.ToolName, err)
+	}
+	if IsOfflineMode() && !isLocalGitRepo(cloneURL) {
+		return &OfflineError{Operation: "git clone", Target: repo}
+	}
+
+	progress := DownloadProgress{
+		ToolName:   p.ToolName,
+		Version:    p.Version,
+		Phase:      PhaseDownloading,
+		URL:        repo,
+		TotalBytes: -1,
+	}
+	reportPhase(callback, progress, PhaseDownloading)
+	err = p.installInto(ctx, func(stagingFolder string) (*InstallRecord, error) {
+		if err := runGit(ctx, "", "clone", "--quiet", "--depth", "1", "--branch", tag, "--", cloneURL, stagingFolder); err != nil {
+			return nil, fmt.Errorf("failed to clone %s at %s: %w", repo, tag, err)
+		}
+		commit, err := gitOutput(ctx, stagingFolder, "rev-parse", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		if err = os.RemoveAll(filepath.Join(stagingFolder, ".git")); err != nil {
+			return nil, err
+		}
+
+		if p.BuildCommand != "" {
+			reportPhase(callback, progress, PhaseExtracting)
+			cmd := shellCommand(ctx, p.Expand(p.BuildCommand))
+			cmd.Dir = stagingFolder
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return nil, fmt.Errorf("failed to build tool %s: %w\n%s", p.ToolName, err, output)
+			}
+		}
+
+		record := newInstallRecord(repo+"@"+tag, "")
+		record.Commit = commit
+		return record, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	reportPhase(callback, progress, PhaseCompleted)
+	return nil
+}
+
+// 本地路径或 file:// 的仓库在离线模式下也可以克隆
+func isLocalGitRepo(repo string) bool {
+	if strings.HasPrefix(repo, "file://") {
+		return true
+	}
+	_, err := os.Stat(repo)
+	return err == nil
+}
+
+func runGit(ctx context.Context, dir string, args ...string) error {
+	_, err := gitOutput(ctx, dir, args...)
+	return err
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// 不要为凭据弹出交互式提示
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
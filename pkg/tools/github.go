@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// LatestVersion 作为 github 来源的版本号时，使用最新的 release，工具安装在
+// 该 release 的版本目录中
+const LatestVersion = "latest"
+
+// GitHub API 地址，GitHub Enterprise 可通过 GITHUB_API_URL 环境变量指定
+const defaultGitHubAPIURL = "https://api.github.com"
+
+// release 信息的大小上限，资产很多的 release 也只有几百 KB
+const maxReleaseInfoSize = 8 << 20
+
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+type githubAsset struct {
+	Name               string `json:"name"`
+	URL                string `json:"url"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+func githubAPIURL() string {
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+		return strings.TrimSuffix(apiURL, "/")
+	}
+	return defaultGitHubAPIURL
+}
+
+func (p *DownloadedTool) githubToken() (string, error) {
+	if p.Token != "" {
+		return config.ExpandEnv(p.Token)
+	}
+	return os.Getenv("GITHUB_TOKEN"), nil
+}
+
+// initLatest 复制配置以便之后改写版本号。安装前先使用已安装的最高版本，
+// 这样不访问网络也能执行；安装时由 resolveLatest 换成最新 release 的版本，
+// 工具安装在该版本的目录中，而不是名为 latest 的目录
+func (p *DownloadedTool) initLatest() {
+	conf := *p.ToolConfig
+	p.ToolConfig = &conf
+	p.followsLatest = true
+	for _, version := range listInstalledVersions(&conf) {
+		if version != LatestVersion && (conf.Version == LatestVersion || config.CompareVersions(version, conf.Version) > 0) {
+			conf.Version = version
+		}
+	}
+}
+
+// resolveLatest 获取最新的 release，并把工具的版本改为它的 tag（去掉前缀 v）。
+// 离线模式下已安装的版本继续使用
+func (p *DownloadedTool) resolveLatest(ctx context.Context) error {
+	if p.latestRelease != nil {
+		return nil
+	}
+	if IsOfflineMode() && p.Version != LatestVersion && p.DoesToolExist() {
+		return nil
+	}
+	token, err := p.githubToken()
+	if err != nil {
+		return fmt.Errorf("invalid token of tool %s: %w", p.ToolName, err)
+	}
+	release, err := fetchGitHubRelease(ctx, p.Repo, "", token)
+	if err != nil {
+		return fmt.Errorf("failed to get the latest github release of tool %s: %w", p.ToolName, err)
+	}
+	version := strings.TrimPrefix(release.TagName, "v")
+	if version == "" || !isBundlePathElement(version) {
+		return fmt.Errorf("invalid tag %q of the latest release of tool %s", release.TagName, p.ToolName)
+	}
+	conf := *p.ToolConfig
+	conf.Version = version
+	p.ToolConfig = &conf
+	p.latestRelease = &release
+	return nil
+}
+
+// resolveGitHubAsset 通过 GitHub API 找到 release 中名为 asset 的文件。
+// 有 token 时通过 API 下载以支持私有仓库，否则使用公开的下载地址
+func (p *DownloadedTool) resolveGitHubAsset(ctx context.Context) (request downloadRequest, err error) {
+	var release githubRelease
+	if p.followsLatest {
+		if err = p.resolveLatest(ctx); err != nil {
+			return
+		}
+		release = *p.latestRelease
+	} else if release, err = p.fetchGitHubRelease(ctx); err != nil {
+		return request, fmt.Errorf("failed to get github release of tool %s: %w", p.ToolName, err)
+	}
+
+	assetName := p.Expand(p.Asset.Value)
+	if assetName == "" {
+		return request, fmt.Errorf("no asset configured for tool %s", p.ToolName)
+	}
+
+	for _, asset := range release.Assets {
+		if asset.Name != assetName {
+			continue
+		}
+		request = downloadRequest{
+			URL:      asset.BrowserDownloadURL,
+			FileName: asset.Name,
+		}
+		token, err := p.githubToken()
+		if err != nil {
+			return request, fmt.Errorf("invalid token of tool %s: %w", p.ToolName, err)
+		}
+		if token != "" {
+			request.URL = asset.URL
+			request.Header = http.Header{
+				"Accept":        {"application/octet-stream"},
+				"Authorization": {"Bearer " + token},
+			}
+		}
+		if err = checkRemoteURL(request.URL); err != nil {
+			return request, fmt.Errorf("asset %s of %s: %w", assetName, p.Repo, err)
+		}
+		return request, nil
+	}
+	return request, fmt.Errorf("asset %s not found in release %s of %s", assetName, release.TagName, p.Repo)
+}
+
+func (p *DownloadedTool) fetchGitHubRelease(ctx context.Context) (release githubRelease, err error) {
+	tag := "v{version}"
+	if p.Tag != "" {
+		tag = p.Tag
+	}
+	tag = p.Expand(tag)
+	token, err := p.githubToken()
+	if err != nil {
+		return release, fmt.Errorf("invalid token of tool %s: %w", p.ToolName, err)
+	}
+	return fetchGitHubRelease(ctx, p.Repo, tag, token)
+}
+
+// fetchGitHubRelease 获取 repo 中 tag 对应的 release，tag 为空时获取最新的 release
+func fetchGitHubRelease(ctx context.Context, repo, tag, token string) (release githubRelease, err error) {
+	if repo == "" || strings.Count(repo, "/") != 1 {
+		return release, fmt.Errorf("invalid repo %q, expected owner/repo", repo)
+	}
+
+	releaseURL := fmt.Sprintf("%s/repos/%s/releases/", githubAPIURL(), repo)
+	if tag == "" {
+		releaseURL += "latest"
+	} else {
+		releaseURL += "tags/" + url.PathEscape(tag)
+	}
+
+	header := http.Header{"Accept": {"application/vnd.github+json"}}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := openDownload(ctx, releaseURL, header)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxReleaseInfoSize)).Decode(&release)
+	return
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	InstallStatusInstalled = "installed"
+	// the tool was installed already
+	InstallStatusSkipped = "skipped"
+	InstallStatusFailed  = "failed"
+)
+
+const defaultInstallConcurrency = 4
+
+type InstallAllOptions struct {
+	// number of tools installed at the same time, 4 by default. The bandwidth
+	// is limited with SetDownloadRateLimit.
+	Concurrency int
+	// called from several goroutines at the same time, may be nil
+	Callback ProgressCallback
+}
+
+type InstallResult struct {
+	ToolName string
+	Version  string
+	Status   string
+	Err      error
+	Duration time.Duration
+}
+
+// InstallAll installs the default version of every configured tool that is not
+// installed yet, together with its dependencies. The results are sorted by tool
+// name, err reports how many tools failed.
+func (p *API) InstallAll(ctx context.Context, opts InstallAllOptions) (results []InstallResult, err error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultInstallConcurrency
+	}
+
+	toolNames := p.ToolNames()
+	results = make([]InstallResult, len(toolNames))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, toolName := range toolNames {
+		result := &results[i]
+		result.ToolName = toolName
+		tool, err := p.GetTool(toolName)
+		if err == nil && tool == nil {
+			err = fmt.Errorf("tool %s not found in config", toolName)
+		}
+		if err != nil {
+			result.Status, result.Err = InstallStatusFailed, err
+			continue
+		}
+		result.Version = tool.GetVersion()
+		if tool.DoesToolExist() {
+			result.Status = InstallStatusSkipped
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				result.Status, result.Err = InstallStatusFailed, ctx.Err()
+				return
+			}
+			startedAt := time.Now()
+			result.Err = p.Install(ctx, result.ToolName, "", opts.Callback)
+			result.Duration = time.Since(startedAt)
+			if result.Err != nil {
+				result.Status = InstallStatusFailed
+			} else {
+				result.Status = InstallStatusInstalled
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == InstallStatusFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		err = fmt.Errorf("failed to install %d of %d tools", failed, len(results))
+	}
+	return
+}
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/version"
+)
+
+// CompleteMarkerFile 是安装的最后一步写入工具目录的标记文件，内容为 InstallRecord，
+// 没有它的目录不会被视为已安装
+const CompleteMarkerFile = ".complete"
+
+// InstallRecord 记录工具是何时、由谁、从哪里安装的
+type InstallRecord struct {
+	InstalledAt time.Time `json:"installedAt"`
+	InstalledBy string    `json:"installedBy,omitempty"`
+	Hostname    string    `json:"hostname,omitempty"`
+	// download URL, path of a local archive, or "reader" for InstallFromReader
+	Source   string `json:"source"`
+	FileName string `json:"fileName,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+	// commit installed from a git source
+	Commit string `json:"commit,omitempty"`
+	// remotetools version that installed the tool
+	InstallerVersion string `json:"installerVersion,omitempty"`
+}
+
+// newInstallRecord 记录从 filePath 安装的工具，没有安装包（如 git 来源）时 filePath 为空
+func newInstallRecord(source, filePath string) *InstallRecord {
+	record := &InstallRecord{
+		InstalledAt:      time.Now().UTC(),
+		Source:           source,
+		InstallerVersion: version.Version,
+	}
+	if u, err := user.Current(); err == nil {
+		record.InstalledBy = u.Username
+	} else if name := os.Getenv("USER"); name != "" {
+		record.InstalledBy = name
+	} else {
+		record.InstalledBy = os.Getenv("USERNAME")
+	}
+	record.Hostname, _ = os.Hostname()
+	if filePath != "" {
+		record.FileName = filepath.Base(filePath)
+		record.SHA256, _ = fileSHA256(filePath)
+	}
+	return record
+}
+
+// readInstallRecord returns nil without an error for installs migrated from
+// before the record was written.
+func readInstallRecord(toolFolder string) (*InstallRecord, error) {
+	data, err := os.ReadFile(completeMarkerPath(toolFolder))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	record := &InstallRecord{}
+	if err = json.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// markerMigrationFile 记录某个 <root>/<os>/<arch> 目录下旧版本安装的工具已经补过标记
+const markerMigrationFile = ".complete-migrated"
+
+// 已检查过的平台目录 -> 是否迁移成功
+var markerMigrations sync.Map
+
+func completeMarkerPath(toolFolder string) string {
+	return filepath.Join(toolFolder, CompleteMarkerFile)
+}
+
+func hasCompleteMarker(toolFolder string) bool {
+	_, err := os.Stat(completeMarkerPath(toolFolder))
+	return err == nil
+}
+
+func writeCompleteMarker(toolFolder string, record *InstallRecord) error {
+	var data []byte
+	if record != nil {
+		var err error
+		if data, err = json.MarshalIndent(record, "", "  "); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(completeMarkerPath(toolFolder), data, 0644)
+}
+
+// migrateLegacyInstalls marks every non-empty tool folder under platformFolder
+// (<root>/<os>/<arch>) as complete, once. Installs made before the marker was
+// introduced have no way to tell whether they were interrupted, so they are
+// trusted one last time. It returns false when the migration could not be
+// recorded, e.g. for a read-only root, in which case unmarked folders are
+// still trusted.
+func migrateLegacyInstalls(platformFolder string) bool {
+	if migrated, ok := markerMigrations.Load(platformFolder); ok {
+		return migrated.(bool)
+	}
+	migrated := doMigrateLegacyInstalls(platformFolder)
+	markerMigrations.Store(platformFolder, migrated)
+	return migrated
+}
+
+func doMigrateLegacyInstalls(platformFolder string) bool {
+	stampPath := filepath.Join(platformFolder, markerMigrationFile)
+	if _, err := os.Stat(stampPath); err == nil {
+		return true
+	}
+
+	toolEntries, err := os.ReadDir(platformFolder)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	for _, toolEntry := range toolEntries {
+		if !toolEntry.IsDir() || strings.HasPrefix(toolEntry.Name(), ".") {
+			continue
+		}
+		toolRoot := filepath.Join(platformFolder, toolEntry.Name())
+		versionEntries, err := os.ReadDir(toolRoot)
+		if err != nil {
+			return false
+		}
+		for _, versionEntry := range versionEntries {
+			if !versionEntry.IsDir() || strings.HasPrefix(versionEntry.Name(), ".") {
+				continue
+			}
+			toolFolder := filepath.Join(toolRoot, versionEntry.Name())
+			if hasCompleteMarker(toolFolder) {
+				continue
+			}
+			if files, err := os.ReadDir(toolFolder); err != nil || len(files) == 0 {
+				continue
+			}
+			if err = writeCompleteMarker(toolFolder, nil); err != nil {
+				return false
+			}
+		}
+	}
+	return os.WriteFile(stampPath, nil, 0644) == nil
+}
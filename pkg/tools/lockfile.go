@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// LockFile 是锁定工具版本的文件的默认文件名，见 WriteLockfile
+const LockFile = "remotetools.lock"
+
+// LockedTool 是锁定的版本，以及各平台安装包的 sha256，键为 "os/arch"
+type LockedTool struct {
+	Version string            `json:"version"`
+	SHA256  map[string]string `json:"sha256,omitempty"`
+}
+
+type Lockfile struct {
+	Tools map[string]LockedTool `json:"tools"`
+}
+
+// ReadLockfile reads a lockfile written by WriteLockfile.
+func ReadLockfile(path string) (lock Lockfile, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &lock); err != nil {
+		return lock, fmt.Errorf("invalid lockfile %s: %w", path, err)
+	}
+	if lock.Tools == nil {
+		lock.Tools = make(map[string]LockedTool)
+	}
+	return
+}
+
+// WriteLockfile writes the version every configured tool currently resolves to,
+// and the sha256 of its artifact for the current platform, to path. The sha256
+// comes from the install record, or from the config when the tool is not
+// installed. Hashes of other platforms already in the file are kept while the
+// version stays the same, so the lockfile can be completed on each platform.
+func (p *API) WriteLockfile(path string) error {
+	old, err := ReadLockfile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	lock := Lockfile{Tools: make(map[string]LockedTool)}
+	platform := CurrentPlatform().String()
+	for _, toolName := range p.ToolNames() {
+		tool, err := p.GetTool(toolName)
+		if err != nil {
+			return err
+		}
+		if tool == nil {
+			continue
+		}
+
+		locked := LockedTool{
+			Version: tool.GetVersion(),
+			SHA256:  make(map[string]string),
+		}
+		if prev, ok := old.Tools[toolName]; ok && prev.Version == locked.Version {
+			for key, sum := range prev.SHA256 {
+				locked.SHA256[key] = sum
+			}
+		}
+		if sum := p.artifactSHA256(tool, toolName); sum != "" {
+			locked.SHA256[platform] = sum
+		}
+		if len(locked.SHA256) == 0 {
+			locked.SHA256 = nil
+		}
+		lock.Tools[toolName] = locked
+	}
+
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// 返回工具当前平台安装包的 sha256，未知时返回空字符串
+func (p *API) artifactSHA256(tool Tool, toolName string) string {
+	if tool.DoesToolExist() {
+		if record, err := tool.GetInstallRecord(); err == nil && record != nil && record.SHA256 != "" {
+			return record.SHA256
+		}
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if toolConfig, ok := p.config.GetVersionConfig(toolName, tool.GetVersion()); ok {
+		return toolConfig.SHA256.Value
+	}
+	return ""
+}
+
+// LoadLockfile makes GetTool resolve the tools in the lockfile to their locked
+// version, and verify downloads against the locked sha256. Locked versions take
+// precedence over project pins.
+func (p *API) LoadLockfile(path string) error {
+	lock, err := ReadLockfile(path)
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.locks = lock.Tools
+	p.lockPath = path
+	return nil
+}
+
+// GetLockedVersion returns the locked version of a tool and the lockfile locking it.
+func (p *API) GetLockedVersion(toolName string) (locked LockedTool, path string, ok bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if locked, ok = p.locks[toolName]; ok {
+		path = p.lockPath
+	}
+	return
+}
+
+func (p *API) getLockedTool(toolName string, locked LockedTool) (tool Tool, err error) {
+	sum := locked.SHA256[CurrentPlatform().String()]
+	p.lock.RLock()
+	toolConfig, configured := p.config.GetVersionConfig(toolName, locked.Version)
+	p.lock.RUnlock()
+
+	_, overridden := GetDevToolOverrideForVersion(toolName, locked.Version)
+	if sum == "" || !configured || overridden {
+		return p.GetToolWithVersion(toolName, locked.Version)
+	}
+	lockedConfig := *toolConfig
+	lockedConfig.SHA256 = config.OsArchSpecificString{Value: sum, Values: []string{sum}}
+	return NewDownloadTool(&lockedConfig), nil
+}
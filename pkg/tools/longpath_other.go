@@ -0,0 +1,11 @@
+//go:build !windows
+
+package tools
+
+func longPath(path string) string {
+	return path
+}
+
+func toLongPathRoot(dest string) string {
+	return dest
+}
@@ -0,0 +1,45 @@
+//go:build windows
+
+package tools
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// 目录路径的上限是 MAX_PATH 减去 12 个字符（留给 8.3 文件名）
+const maxShortPath = 260 - 12
+
+// longPath converts path to an extended-length path when it could exceed
+// MAX_PATH, so deep tool layouts can still be executed.
+func longPath(path string) string {
+	if strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil || len(absPath) < maxShortPath {
+		return path
+	}
+	return extendedLengthPath(absPath)
+}
+
+// toLongPathRoot 用于解压目标目录：解压前无法预知条目路径的长度，
+// 所以总是转换为 \\?\ 路径，拼接出的条目路径也就不受 MAX_PATH 限制
+func toLongPathRoot(dest string) string {
+	if strings.HasPrefix(dest, `\\?\`) {
+		return dest
+	}
+	absPath, err := filepath.Abs(dest)
+	if err != nil {
+		return dest
+	}
+	return extendedLengthPath(absPath)
+}
+
+func extendedLengthPath(absPath string) string {
+	if strings.HasPrefix(absPath, `\\`) {
+		// \\server\share\... -> \\?\UNC\server\share\...
+		return `\\?\UNC\` + absPath[2:]
+	}
+	return `\\?\` + absPath
+}
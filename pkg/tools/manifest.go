@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ManifestFile 在安装完成时写入工具目录，记录每个文件的大小和 sha256，Verify 据此检查文件
+const ManifestFile = ".rt_manifest.json"
+
+// ErrNoManifest is returned by Verify for installs made before the manifest was
+// written.
+var ErrNoManifest = errors.New("no install manifest")
+
+type ManifestEntry struct {
+	// slash separated path relative to the tool folder
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type Manifest struct {
+	InstalledAt time.Time `json:"installedAt"`
+	// download URL or other source of the install, see InstallRecord
+	Source string          `json:"source"`
+	Files  []ManifestEntry `json:"files"`
+}
+
+const (
+	FileMissing  = "missing"
+	FileModified = "modified"
+)
+
+// FileProblem is a file of an installed tool that does not match the manifest.
+type FileProblem struct {
+	Path string `json:"path"`
+	// FileMissing or FileModified
+	Problem string `json:"problem"`
+}
+
+var strictInstallCheck atomic.Bool
+
+// SetStrictInstallCheck makes DoesToolExist compare the files of a tool with
+// its manifest, so a tool with missing files or files of the wrong size is not
+// installed and the next Install replaces it. Only sizes are compared, Verify
+// also checks the hashes. Installs without a manifest are not checked.
+func SetStrictInstallCheck(enabled bool) {
+	strictInstallCheck.Store(enabled)
+}
+
+// SetStrictInstallCheck sets strict install checks, see the package level SetStrictInstallCheck.
+func (p *API) SetStrictInstallCheck(enabled bool) {
+	SetStrictInstallCheck(enabled)
+}
+
+func IsStrictInstallCheck() bool {
+	return strictInstallCheck.Load()
+}
+
+// writeManifest 记录工具目录中的所有普通文件，元数据文件除外
+func writeManifest(toolFolder string, record *InstallRecord) error {
+	manifest := Manifest{InstalledAt: time.Now().UTC()}
+	if record != nil {
+		manifest.InstalledAt = record.InstalledAt
+		manifest.Source = record.Source
+	}
+	err := filepath.WalkDir(toolFolder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(toolFolder, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if isToolMetadataFile(rel) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, ManifestEntry{Path: rel, Size: info.Size(), SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(toolFolder, ManifestFile), data, 0644)
+}
+
+func readManifest(toolFolder string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(toolFolder, ManifestFile))
+	if os.IsNotExist(err) {
+		return nil, ErrNoManifest
+	}
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ManifestFile, err)
+	}
+	return manifest, nil
+}
+
+// checkManifest 比较工具目录与清单，checkHashes 为 false 时只比较文件大小
+func checkManifest(toolFolder string, manifest *Manifest, checkHashes bool) (problems []FileProblem) {
+	for _, entry := range manifest.Files {
+		path := filepath.Join(toolFolder, filepath.FromSlash(entry.Path))
+		info, err := os.Stat(path)
+		if err != nil {
+			problems = append(problems, FileProblem{Path: entry.Path, Problem: FileMissing})
+			continue
+		}
+		if info.Size() != entry.Size {
+			problems = append(problems, FileProblem{Path: entry.Path, Problem: FileModified})
+			continue
+		}
+		if checkHashes {
+			if sum, err := fileSHA256(path); err != nil || sum != entry.SHA256 {
+				problems = append(problems, FileProblem{Path: entry.Path, Problem: FileModified})
+			}
+		}
+	}
+	return
+}
+
+// passesStrictCheck 用于 DoesToolExist，没有清单的安装视为通过
+func passesStrictCheck(toolFolder string) bool {
+	if !IsStrictInstallCheck() {
+		return true
+	}
+	manifest, err := readManifest(toolFolder)
+	if err == ErrNoManifest {
+		return true
+	}
+	return err == nil && len(checkManifest(toolFolder, manifest, false)) == 0
+}
+
+// Verify compares the files of an installed tool with the manifest written at
+// install time and returns the files that are missing or were modified. Files
+// added after the install are not reported. ErrNoManifest is returned for
+// installs without a manifest.
+func (p *API) Verify(tool Tool) (problems []FileProblem, err error) {
+	downloaded, ok := tool.(*DownloadedTool)
+	if !ok {
+		return nil, fmt.Errorf("tool %s uses a dev override and cannot be verified", tool.GetMetadata().Name)
+	}
+	toolFolder := filepath.FromSlash(downloaded.GetToolFolder())
+	if !hasCompleteMarker(toolFolder) {
+		return nil, fmt.Errorf("tool %s %s is not installed", downloaded.ToolName, downloaded.Version)
+	}
+	manifest, err := readManifest(toolFolder)
+	if err != nil {
+		return nil, err
+	}
+	return checkManifest(toolFolder, manifest, true), nil
+}
@@ -0,0 +1,213 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// MirrorIndexFile 是镜像目录中记录所有安装包及其哈希的索引文件
+const MirrorIndexFile = "index.json"
+
+type Platform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+func (p Platform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+// CurrentPlatform returns the platform the program is running on.
+func CurrentPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// KnownPlatforms are the platforms tried when mirroring for all platforms.
+var KnownPlatforms = []Platform{
+	{"darwin", "amd64"}, {"darwin", "arm64"},
+	{"linux", "386"}, {"linux", "amd64"}, {"linux", "arm"}, {"linux", "arm64"},
+	{"windows", "386"}, {"windows", "amd64"}, {"windows", "arm64"},
+}
+
+type MirrorEntry struct {
+	Tool     string `json:"tool"`
+	Version  string `json:"version"`
+	Platform `json:"platform"`
+	URL      string `json:"url"`
+	// slash separated path relative to the mirror folder
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type MirrorIndex struct {
+	CreatedAt time.Time     `json:"createdAt"`
+	Entries   []MirrorEntry `json:"entries"`
+}
+
+// Mirror downloads the artifacts of the given tools (all configured tools when
+// toolNames is empty) for every platform into dest, laid out as
+// <tool>/<version>/<os>-<arch>/<file> next to a <file>.sha256, and writes
+// MirrorIndexFile. Downloads are verified like installs, with the configured
+// sha256, checksumUrl and signature. Platforms without a download URL (or
+// asset for github tools) are skipped, git tools can not be mirrored. An
+// artifact shared by several platforms is downloaded once into <os> or any
+// instead.
+func (p *API) Mirror(ctx context.Context, dest string, platforms []Platform, toolNames []string) (index MirrorIndex, err error) {
+	conf := p.GetConfig()
+	if conf.ToolConfigs == nil {
+		err = fmt.Errorf("config is not loaded")
+		return
+	}
+	if len(toolNames) == 0 {
+		toolNames = p.ToolNames()
+	}
+
+	index.CreatedAt = time.Now().UTC()
+	for _, toolName := range toolNames {
+		toolConfig, ok := conf.ToolConfigs[toolName]
+		if !ok {
+			return index, fmt.Errorf("tool %s not found in config", toolName)
+		}
+		artifact := toolConfig.DownloadURL
+		switch toolConfig.Source {
+		case "", SourceURL:
+		case SourceGitHub:
+			artifact = toolConfig.Asset
+		default:
+			return index, fmt.Errorf("tool %s with source %q can not be mirrored", toolName, toolConfig.Source)
+		}
+
+		// 按第一个下载地址分组，多个平台共用的安装包只下载一次
+		var urls []string
+		groups := make(map[string]*mirrorGroup)
+		for _, platform := range platforms {
+			if value, err := artifact.ValueFor(platform.OS, platform.Arch); err != nil || value == "" {
+				continue
+			}
+			platformConfig, err := toolConfig.ForPlatform(platform.OS, platform.Arch)
+			if err != nil {
+				return index, fmt.Errorf("failed to mirror %s for %s: %w", toolName, platform, err)
+			}
+			tool := NewDownloadTool(platformConfig)
+			requests, err := tool.resolveDownloads(ctx)
+			if err != nil {
+				return index, fmt.Errorf("failed to mirror %s for %s: %w", toolName, platform, err)
+			}
+			url := requests[0].URL
+			group, ok := groups[url]
+			if !ok {
+				group = &mirrorGroup{tool: tool, requests: requests}
+				groups[url] = group
+				urls = append(urls, url)
+			}
+			group.platforms = append(group.platforms, platform)
+		}
+		for _, url := range urls {
+			group := groups[url]
+			entry, err := group.tool.mirrorArtifactFromAny(ctx, dest, mirrorFolderName(group.platforms), group.requests)
+			if err != nil {
+				return index, fmt.Errorf("failed to mirror %s from %s: %w", toolName, url, err)
+			}
+			for _, platform := range group.platforms {
+				entry.Platform = platform
+				index.Entries = append(index.Entries, entry)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return
+	}
+	err = os.WriteFile(filepath.Join(dest, MirrorIndexFile), data, 0644)
+	return
+}
+
+// mirrorGroup 是共用同一安装包的平台，tool 为第一个平台的配置，用于下载和校验
+type mirrorGroup struct {
+	tool      *DownloadedTool
+	requests  []downloadRequest
+	platforms []Platform
+}
+
+// mirrorFolderName 为一组共用同一安装包的平台命名目录：单个平台为 <os>-<arch>，
+// 同一系统的多个架构为 <os>，否则为 any
+func mirrorFolderName(platforms []Platform) string {
+	if len(platforms) == 1 {
+		return platforms[0].OS + "-" + platforms[0].Arch
+	}
+	for _, platform := range platforms[1:] {
+		if platform.OS != platforms[0].OS {
+			return "any"
+		}
+	}
+	return platforms[0].OS
+}
+
+// mirrorArtifactFromAny 依次尝试各个下载地址
+func (p *DownloadedTool) mirrorArtifactFromAny(ctx context.Context, dest, folderName string, requests []downloadRequest) (entry MirrorEntry, err error) {
+	for _, request := range requests {
+		if entry, err = p.mirrorArtifact(ctx, dest, folderName, request); err == nil || ctx.Err() != nil {
+			return
+		}
+	}
+	return
+}
+
+// mirrorArtifact 先下载到 dest 中的临时目录，校验通过后再移动到镜像目录
+func (p *DownloadedTool) mirrorArtifact(ctx context.Context, dest, folderName string, request downloadRequest) (entry MirrorEntry, err error) {
+	if err = os.MkdirAll(dest, 0755); err != nil {
+		return
+	}
+	tmpDir, err := os.MkdirTemp(dest, ".tmp_mirror_")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	downloadPath, progress, err := p.download(ctx, request, tmpDir, nil)
+	if err != nil {
+		return
+	}
+	if err = p.verifyArtifact(ctx, downloadPath, progress, nil); err != nil {
+		return
+	}
+
+	fileName := filepath.Base(downloadPath)
+	relPath := p.ToolName + "/" + p.Version + "/" + folderName + "/" + fileName
+	filePath := filepath.Join(dest, filepath.FromSlash(relPath))
+	if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return
+	}
+	if err = os.Rename(downloadPath, filePath); err != nil {
+		return
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return
+	}
+
+	checksum, err := fileSHA256(filePath)
+	if err != nil {
+		return
+	}
+	if err = os.WriteFile(filePath+".sha256", []byte(checksum+"  "+fileName+"\n"), 0644); err != nil {
+		return
+	}
+
+	entry = MirrorEntry{
+		Tool:    p.ToolName,
+		Version: p.Version,
+		URL:     request.source(),
+		Path:    relPath,
+		Size:    info.Size(),
+		SHA256:  checksum,
+	}
+	return
+}
@@ -0,0 +1,366 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// OCIScheme 是 downloadUrl 中 OCI registry 制品的前缀，格式为
+// oci://registry/repo:tag 或 oci://registry/repo@sha256:<digest>
+const OCIScheme = "oci://"
+
+const (
+	ociManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexMediaType       = "application/vnd.oci.image.index.v1+json"
+	dockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+	dockerManifestListType  = "application/vnd.docker.distribution.manifest.list.v2+json"
+	ociAcceptMediaTypes     = ociManifestMediaType + ", " + ociIndexMediaType + ", " + dockerManifestMediaType + ", " + dockerManifestListType
+
+	// ORAS 用此注解记录推送的文件名
+	ociTitleAnnotation = "org.opencontainers.image.title"
+	ociDigestPrefix    = "sha256:"
+	defaultOCITag      = "latest"
+	maxOCIManifestSize = 4 << 20
+)
+
+type ociReference struct {
+	Registry   string
+	Repository string
+	// tag or digest
+	Reference string
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+	// entries of an image index
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+func parseOCIReference(rawURL string) (ref ociReference, err error) {
+	rest := strings.TrimPrefix(rawURL, OCIScheme)
+	registry, repository, ok := strings.Cut(rest, "/")
+	if !ok || registry == "" || repository == "" {
+		return ref, fmt.Errorf("invalid oci reference %s, expected oci://registry/repo:tag", rawURL)
+	}
+	ref.Registry = registry
+	if name, digest, ok := strings.Cut(repository, "@"); ok {
+		ref.Repository, ref.Reference = name, digest
+	} else if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		ref.Repository, ref.Reference = repository[:i], repository[i+1:]
+	} else {
+		ref.Repository, ref.Reference = repository, defaultOCITag
+	}
+	if ref.Repository == "" || ref.Reference == "" {
+		return ref, fmt.Errorf("invalid oci reference %s, expected oci://registry/repo:tag", rawURL)
+	}
+	return
+}
+
+// 本机的 registry 与 ORAS 的 --plain-http 一样使用 http
+func (p ociReference) baseURL() string {
+	host := (&url.URL{Host: p.Registry}).Hostname()
+	scheme := "https"
+	if host == "localhost" || host == "127.0.0.1" || host == "::1" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s", scheme, p.Registry, p.Repository)
+}
+
+// ociClient 访问一个 registry 仓库，按 WWW-Authenticate 的要求换取 bearer token
+type ociClient struct {
+	ref ociReference
+	// bearer token from the config, used as is
+	token string
+	// basic auth from the docker config, used to get a bearer token
+	basicAuth string
+	header    http.Header
+}
+
+func (p *DownloadedTool) newOCIClient(ref ociReference) (client *ociClient, err error) {
+	client = &ociClient{ref: ref}
+	if p.Token != "" {
+		if client.token, err = config.ExpandEnv(p.Token); err != nil {
+			return nil, fmt.Errorf("invalid token of tool %s: %w", p.ToolName, err)
+		}
+	} else {
+		client.basicAuth = dockerConfigAuth(ref.Registry)
+	}
+	return
+}
+
+// resolveOCIArtifact 取得 manifest 并找到要下载的 layer，下载后按 layer 的 digest 校验
+func (p *DownloadedTool) resolveOCIArtifact(ctx context.Context, rawURL string) (request downloadRequest, err error) {
+	ref, err := parseOCIReference(rawURL)
+	if err != nil {
+		return
+	}
+	client, err := p.newOCIClient(ref)
+	if err != nil {
+		return
+	}
+	manifest, err := client.fetchManifest(ctx, ref.Reference)
+	if err != nil {
+		return request, fmt.Errorf("failed to get manifest of %s: %w", rawURL, err)
+	}
+	if len(manifest.Manifests) > 0 {
+		digest := ""
+		for _, entry := range manifest.Manifests {
+			if entry.Platform != nil && entry.Platform.OS == runtime.GOOS && entry.Platform.Architecture == runtime.GOARCH {
+				digest = entry.Digest
+				break
+			}
+		}
+		if digest == "" {
+			return request, fmt.Errorf("no manifest for %s/%s in %s", runtime.GOOS, runtime.GOARCH, rawURL)
+		}
+		if manifest, err = client.fetchManifest(ctx, digest); err != nil {
+			return request, fmt.Errorf("failed to get manifest of %s: %w", rawURL, err)
+		}
+	}
+
+	layer, err := p.selectOCILayer(manifest.Layers)
+	if err != nil {
+		return request, fmt.Errorf("%w in %s", err, rawURL)
+	}
+	if !strings.HasPrefix(layer.Digest, ociDigestPrefix) {
+		return request, fmt.Errorf("unsupported digest %s in %s", layer.Digest, rawURL)
+	}
+
+	blobURL := ref.baseURL() + "/blobs/" + layer.Digest
+	header, err := client.authorize(ctx, blobURL)
+	if err != nil {
+		return
+	}
+	return downloadRequest{
+		URL:      blobURL,
+		FileName: ociLayerFileName(layer),
+		Header:   header,
+		SHA256:   strings.TrimPrefix(layer.Digest, ociDigestPrefix),
+	}, nil
+}
+
+// 只有一个 layer 时直接使用，多个 layer 时按 asset 匹配 layer 的标题
+func (p *DownloadedTool) selectOCILayer(layers []ociDescriptor) (layer ociDescriptor, err error) {
+	asset := p.Expand(p.Asset.Value)
+	if asset == "" {
+		if len(layers) == 1 {
+			return layers[0], nil
+		}
+		return layer, fmt.Errorf("%d layers found, set asset to the title of the layer to install", len(layers))
+	}
+	for _, layer := range layers {
+		if layer.Annotations[ociTitleAnnotation] == asset {
+			return layer, nil
+		}
+	}
+	return layer, fmt.Errorf("no layer titled %s", asset)
+}
+
+// ORAS 推送的文件以标题注解记录文件名，没有时按 mediaType 推断扩展名
+func ociLayerFileName(layer ociDescriptor) string {
+	if title := filepath.Base(layer.Annotations[ociTitleAnnotation]); title != "." && title != "/" && title != "" {
+		return title
+	}
+	name := strings.TrimPrefix(layer.Digest, ociDigestPrefix)
+	switch {
+	case strings.HasSuffix(layer.MediaType, "tar+gzip"):
+		return name + ".tar.gz"
+	case strings.HasSuffix(layer.MediaType, "tar+zstd"):
+		return name + ".tar.zst"
+	case strings.HasSuffix(layer.MediaType, "zip"):
+		return name + ".zip"
+	default:
+		return name
+	}
+}
+
+func (p *ociClient) fetchManifest(ctx context.Context, reference string) (manifest ociManifest, err error) {
+	manifestURL := p.ref.baseURL() + "/manifests/" + reference
+	header, err := p.authorize(ctx, manifestURL)
+	if err != nil {
+		return
+	}
+	header.Set("Accept", ociAcceptMediaTypes)
+	resp, err := openDownload(ctx, manifestURL, header)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOCIManifestSize))
+	if err != nil {
+		return
+	}
+	// 按 digest 引用时校验 manifest 本身
+	if strings.HasPrefix(reference, ociDigestPrefix) {
+		sum := sha256.Sum256(data)
+		if actual := ociDigestPrefix + hex.EncodeToString(sum[:]); actual != reference {
+			return manifest, fmt.Errorf("%w for manifest: expected %s, got %s", ErrChecksumMismatch, reference, actual)
+		}
+	}
+	err = json.Unmarshal(data, &manifest)
+	return
+}
+
+// authorize returns the header to access url with. Registries that allow
+// anonymous access get no Authorization header, otherwise a bearer token is
+// requested from the realm in the WWW-Authenticate challenge.
+func (p *ociClient) authorize(ctx context.Context, targetURL string) (http.Header, error) {
+	if p.header != nil {
+		return p.header.Clone(), nil
+	}
+	if p.token != "" {
+		p.header = http.Header{"Authorization": {"Bearer " + p.token}}
+		return p.header.Clone(), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ociAcceptMediaTypes)
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		p.header = http.Header{}
+		return p.header.Clone(), nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	scheme, params := parseAuthChallenge(challenge)
+	switch {
+	case strings.EqualFold(scheme, "basic") && p.basicAuth != "":
+		p.header = http.Header{"Authorization": {"Basic " + p.basicAuth}}
+	case strings.EqualFold(scheme, "bearer") && params["realm"] != "":
+		token, err := p.fetchToken(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		p.header = http.Header{"Authorization": {"Bearer " + token}}
+	default:
+		return nil, fmt.Errorf("unsupported registry auth challenge %q", challenge)
+	}
+	return p.header.Clone(), nil
+}
+
+func (p *ociClient) fetchToken(ctx context.Context, params map[string]string) (string, error) {
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", err
+	}
+	query := tokenURL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + p.ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+	tokenURL.RawQuery = query.Encode()
+
+	var header http.Header
+	if p.basicAuth != "" {
+		header = http.Header{"Authorization": {"Basic " + p.basicAuth}}
+	}
+	resp, err := openRemoteDownload(ctx, tokenURL.String(), header)
+	if err != nil {
+		return "", fmt.Errorf("failed to get registry token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxOCIManifestSize)).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid registry token response: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("registry token response has no token")
+}
+
+// parseAuthChallenge 解析 `Bearer realm="...",service="...",scope="..."`
+func parseAuthChallenge(challenge string) (scheme string, params map[string]string) {
+	params = make(map[string]string)
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return
+}
+
+// dockerConfigAuth 从 docker 的配置文件中读取 registry 的登录信息（base64 的 user:password），
+// 以复用 docker login 的结果。不支持 credential helper
+func dockerConfigAuth(registry string) string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return ""
+	}
+	var conf struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err = json.Unmarshal(data, &conf); err != nil {
+		return ""
+	}
+	for _, key := range []string{registry, "https://" + registry, "http://" + registry} {
+		if auth := conf.Auths[key].Auth; auth != "" {
+			if _, err := base64.StdEncoding.DecodeString(auth); err == nil {
+				return auth
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrOffline is wrapped by the errors of operations refused in offline mode.
+var ErrOffline = errors.New("offline mode")
+
+// OfflineError is returned instead of accessing the network in offline mode,
+// see SetOfflineMode.
+type OfflineError struct {
+	// e.g. "download" or "git clone"
+	Operation string
+	Target    string
+}
+
+func (e *OfflineError) Error() string {
+	return fmt.Sprintf("%s %s is not possible in offline mode", e.Operation, e.Target)
+}
+
+// Unwrap makes errors.Is(err, ErrOffline) true.
+func (e *OfflineError) Unwrap() error {
+	return ErrOffline
+}
+
+var offlineMode atomic.Bool
+
+// SetOfflineMode makes every operation that needs the network (downloads,
+// github/oci/s3 lookups, git clones) fail at once with an OfflineError instead
+// of waiting for a timeout. file:// URLs keep working. Versions are then only
+// picked among the installed ones, see GetToolByConstraint and GetToolLatest.
+func SetOfflineMode(enabled bool) {
+	offlineMode.Store(enabled)
+}
+
+// SetOfflineMode sets offline mode for all downloads, see the package level SetOfflineMode.
+func (p *API) SetOfflineMode(enabled bool) {
+	SetOfflineMode(enabled)
+}
+
+func IsOfflineMode() bool {
+	return offlineMode.Load()
+}
+
+func (p *API) IsOfflineMode() bool {
+	return IsOfflineMode()
+}
+
+// offlineTransport 在离线模式下拒绝 file:// 以外的请求
+type offlineTransport struct {
+	next http.RoundTripper
+}
+
+func (t offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if IsOfflineMode() && req.URL.Scheme != "file" {
+		return nil, &OfflineError{Operation: req.Method, Target: req.URL.Redacted()}
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 打包时所有条目使用同一个时间戳（zip 能表示的最早时间），保证结果可复现
+var packageModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type packageEntry struct {
+	name string
+	path string
+	mode os.FileMode
+	size int64
+}
+
+// PackageFolder archives dir into dest (.zip or .tar.gz) and returns its sha256.
+// Entries are sorted, timestamps and owners are fixed and modes are reduced to
+// 0755/0644, so the same folder always produces the same artifact. entry, a
+// slash separated path relative to dir, is always marked executable.
+func PackageFolder(dir, dest, entry string) (checksum string, err error) {
+	entries, err := listPackageEntries(dir, entry)
+	if err != nil {
+		return
+	}
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return
+	}
+	if strings.HasSuffix(dest, ".zip") {
+		err = writeZipPackage(out, entries)
+	} else if strings.HasSuffix(dest, ".tar.gz") {
+		err = writeTarGzPackage(out, entries)
+	} else {
+		err = fmt.Errorf("unsupported file format: %s", dest)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest)
+		return
+	}
+	return fileSHA256(dest)
+}
+
+func listPackageEntries(dir, entry string) (entries []packageEntry, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		// 符号链接指向的文件按普通文件打包，解压时不支持链接
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if d.Type()&os.ModeSymlink != 0 {
+				return fmt.Errorf("symlinked folders are not supported: %s", path)
+			}
+			entries = append(entries, packageEntry{name: name + "/", path: path, mode: os.ModeDir | 0755})
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("unsupported file type: %s", path)
+		}
+
+		mode := os.FileMode(0644)
+		if info.Mode()&0111 != 0 || name == entry {
+			mode = 0755
+		}
+		entries = append(entries, packageEntry{name: name, path: path, mode: mode, size: info.Size()})
+		return nil
+	})
+	return
+}
+
+func writeZipPackage(w io.Writer, entries []packageEntry) error {
+	zw := zip.NewWriter(w)
+	for _, entry := range entries {
+		header := &zip.FileHeader{
+			Name:     entry.name,
+			Method:   zip.Deflate,
+			Modified: packageModTime,
+		}
+		header.SetMode(entry.mode)
+		if entry.mode.IsDir() {
+			header.Method = zip.Store
+		}
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if entry.mode.IsDir() {
+			continue
+		}
+		if err = copyFileTo(fw, entry.path); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeTarGzPackage(w io.Writer, entries []packageEntry) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:    entry.name,
+			Mode:    int64(entry.mode.Perm()),
+			ModTime: packageModTime,
+			Format:  tar.FormatPAX,
+		}
+		if entry.mode.IsDir() {
+			header.Typeflag = tar.TypeDir
+		} else {
+			header.Typeflag = tar.TypeReg
+			header.Size = entry.size
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if entry.mode.IsDir() {
+			continue
+		}
+		if err := copyFileTo(tw, entry.path); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func copyFileTo(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
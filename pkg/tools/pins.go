@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 项目内固定工具版本的文件，从工作目录开始向上查找，使用最近的一个：
+//
+//	.remotetools.json:    {"tools": {"dotnet": "8.0.5"}}
+//	.remotetools-version: 每行 "dotnet 8.0.5"，# 开头为注释
+const (
+	PinFile        = ".remotetools.json"
+	PinVersionFile = ".remotetools-version"
+)
+
+const (
+	InstallModeGlobal  = "global"
+	InstallModeProject = "project"
+)
+
+// ProjectToolFolder 是项目模式下工具的安装目录，相对于项目目录
+const ProjectToolFolder = ".remotetools"
+
+type pinFile struct {
+	Tools map[string]string `json:"tools"`
+}
+
+// FindPinFile returns the nearest pin file in dir or one of its parents, or ""
+// when there is none.
+func FindPinFile(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		for _, name := range []string{PinFile, PinVersionFile} {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ProjectFolder returns the folder containing the nearest pin file, or dir
+// itself when there is none.
+func ProjectFolder(dir string) string {
+	if path := FindPinFile(dir); path != "" {
+		return filepath.Dir(path)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// LoadPins reads tool versions from a file in the PinFile or PinVersionFile format.
+func LoadPins(path string) (pins map[string]string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	if filepath.Base(path) == PinVersionFile {
+		return parsePinVersionFile(data, path)
+	}
+
+	var file pinFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid pin file %s: %w", path, err)
+	}
+	pins = file.Tools
+	if pins == nil {
+		pins = make(map[string]string)
+	}
+	return
+}
+
+func parsePinVersionFile(data []byte, path string) (pins map[string]string, err error) {
+	pins = make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid pin file %s, line %d: expected \"<tool> <version>\"", path, lineNo)
+		}
+		pins[fields[0]] = fields[1]
+	}
+	return pins, scanner.Err()
+}
+
+// LoadProjectPins looks for the nearest pin file from dir upward and makes
+// GetTool resolve pinned tools to the pinned version. Without a pin file the
+// existing pins are cleared. Pins are only used after this is called, the CLI
+// calls it with the working directory.
+func (p *API) LoadProjectPins(dir string) error {
+	var pins map[string]string
+	path := FindPinFile(dir)
+	if path != "" {
+		var err error
+		if pins, err = LoadPins(path); err != nil {
+			return err
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.pins = pins
+	p.pinPath = path
+	return nil
+}
+
+// GetPinnedVersion returns the version pinned for a tool and the file pinning it.
+func (p *API) GetPinnedVersion(toolName string) (version, path string) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if version = p.pins[toolName]; version != "" {
+		path = p.pinPath
+	}
+	return
+}
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 进程被终止后最多等待这么久让它退出
+const processExitTimeout = 10 * time.Second
+
+type runningProcess struct {
+	process *os.Process
+	exited  chan struct{}
+}
+
+// 由 Execute 启动且仍在运行的进程，按工具目录分组，卸载前会被终止
+var runningProcesses = struct {
+	sync.Mutex
+	byFolder map[string]map[*runningProcess]struct{}
+}{
+	byFolder: make(map[string]map[*runningProcess]struct{}),
+}
+
+// TerminateGracePeriod 是 ExecuteContext 的 ctx 结束（如超时）后发送 SIGTERM 到 SIGKILL 之间的等待时间。
+// Windows 没有 SIGTERM，进程直接被终止
+var TerminateGracePeriod = 5 * time.Second
+
+// TimeoutError is returned by ExecuteWithTimeout, and ExecuteContext when the
+// deadline of ctx is exceeded, after the tool process has been stopped.
+type TimeoutError struct {
+	ToolName string
+	Version  string
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("tool %s %s timed out", e.ToolName, e.Version)
+}
+
+// Unwrap makes errors.Is(err, context.DeadlineExceeded) true.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// runTracked runs cmd like cmd.Run and records the process under toolFolder
+// while it is running. When ctx is done the process is stopped, see
+// TerminateGracePeriod, and ctx.Err() is returned.
+func runTracked(ctx context.Context, cmd *exec.Cmd, toolFolder string) error {
+	wait, err := startTracked(ctx, cmd, toolFolder)
+	if err != nil {
+		return err
+	}
+	return wait()
+}
+
+// startTracked 启动 cmd，返回的 wait 与 runTracked 的行为一致，必须被调用
+func startTracked(ctx context.Context, cmd *exec.Cmd, toolFolder string) (wait func() error, err error) {
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	exited := make(chan struct{})
+	stopped := make(chan bool, 1)
+	go func() {
+		select {
+		case <-exited:
+			stopped <- false
+			return
+		case <-ctx.Done():
+		}
+		if err := terminateProcess(cmd.Process); err != nil {
+			cmd.Process.Kill()
+		}
+		select {
+		case <-exited:
+		case <-time.After(TerminateGracePeriod):
+			cmd.Process.Kill()
+		}
+		stopped <- true
+	}()
+
+	toolFolder = filepath.Clean(toolFolder)
+	proc := &runningProcess{process: cmd.Process, exited: make(chan struct{})}
+
+	runningProcesses.Lock()
+	processes := runningProcesses.byFolder[toolFolder]
+	if processes == nil {
+		processes = make(map[*runningProcess]struct{})
+		runningProcesses.byFolder[toolFolder] = processes
+	}
+	processes[proc] = struct{}{}
+	runningProcesses.Unlock()
+
+	return func() error {
+		defer func() {
+			runningProcesses.Lock()
+			delete(processes, proc)
+			if len(processes) == 0 {
+				delete(runningProcesses.byFolder, toolFolder)
+			}
+			runningProcesses.Unlock()
+			close(proc.exited)
+		}()
+		err := cmd.Wait()
+		close(exited)
+		if <-stopped {
+			return ctx.Err()
+		}
+		return err
+	}, nil
+}
+
+func terminateProcess(process *os.Process) error {
+	if runtime.GOOS == "windows" {
+		return process.Kill()
+	}
+	return process.Signal(syscall.SIGTERM)
+}
+
+func runningProcessesIn(toolFolder string) (result []*runningProcess) {
+	runningProcesses.Lock()
+	defer runningProcesses.Unlock()
+	for proc := range runningProcesses.byFolder[filepath.Clean(toolFolder)] {
+		result = append(result, proc)
+	}
+	return
+}
+
+// killToolProcesses 终止由 Execute 从 toolFolder 启动的进程并等待其退出，
+// 否则 Windows 上文件仍被占用，工具目录无法改名删除
+func killToolProcesses(toolFolder string) error {
+	processes := runningProcessesIn(toolFolder)
+	for _, proc := range processes {
+		if err := proc.process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return fmt.Errorf("failed to kill process %d: %w", proc.process.Pid, err)
+		}
+	}
+	timeout := time.After(processExitTimeout)
+	for _, proc := range processes {
+		select {
+		case <-proc.exited:
+		case <-timeout:
+			return fmt.Errorf("process %d did not exit after being killed", proc.process.Pid)
+		}
+	}
+	return nil
+}
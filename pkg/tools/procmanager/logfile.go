@@ -0,0 +1,96 @@
+package procmanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	defaultMaxLogSize  = 10 * 1024 * 1024
+	defaultMaxLogFiles = 3
+)
+
+// rotatingFile 是服务的日志文件，超过 maxSize 后依次改名为 .1、.2……，最多保留 maxFiles 个旧文件
+type rotatingFile struct {
+	lock     sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxLogSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = defaultMaxLogFiles
+	}
+	p := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := p.open(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *rotatingFile) open() error {
+	file, err := os.OpenFile(p.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	p.file, p.size = file, info.Size()
+	return nil
+}
+
+func (p *rotatingFile) Write(b []byte) (n int, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.file == nil {
+		return 0, os.ErrClosed
+	}
+	if p.size > 0 && p.size+int64(len(b)) > p.maxSize {
+		if err = p.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err = p.file.Write(b)
+	p.size += int64(n)
+	return
+}
+
+func (p *rotatingFile) rotate() error {
+	if err := p.file.Close(); err != nil {
+		return err
+	}
+	p.file = nil
+	os.Remove(fmt.Sprintf("%s.%d", p.path, p.maxFiles))
+	for i := p.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", p.path, i), fmt.Sprintf("%s.%d", p.path, i+1))
+	}
+	if err := os.Rename(p.path, p.path+".1"); err != nil {
+		return err
+	}
+	return p.open()
+}
+
+func (p *rotatingFile) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.file == nil {
+		return nil
+	}
+	err := p.file.Close()
+	p.file = nil
+	return err
+}
@@ -0,0 +1,321 @@
+// Package procmanager runs tools as long-lived background services.
+//
+// A Manager starts a tool with Start and keeps it running according to its
+// RestartPolicy. The output of the tool goes to a log file that is rotated by
+// size, and the PID of the running process is written next to it, so other
+// programs can find it.
+package procmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/tools"
+)
+
+// RestartPolicy 决定服务进程退出后是否自动重启
+type RestartPolicy string
+
+const (
+	RestartNever     RestartPolicy = "never"
+	RestartOnFailure RestartPolicy = "on-failure"
+	RestartAlways    RestartPolicy = "always"
+)
+
+const defaultRestartDelay = time.Second
+
+type State string
+
+const (
+	StateRunning    State = "running"
+	StateRestarting State = "restarting"
+	StateStopped    State = "stopped"
+	// the process exited and is not restarted, see RestartPolicy
+	StateExited State = "exited"
+)
+
+type ServiceConfig struct {
+	// Name identifies the service in the Manager, the tool name by default.
+	Name    string
+	Tool    tools.Tool
+	Args    []string
+	Options tools.ExecOptions
+	// RestartNever by default.
+	Restart      RestartPolicy
+	RestartDelay time.Duration
+	// 0 restarts without limit.
+	MaxRestarts int
+	// <dir>/<name>.log by default. It is rotated when it grows over MaxLogSize
+	// (10MB by default), keeping MaxLogFiles (3 by default) old files.
+	LogFile     string
+	MaxLogSize  int64
+	MaxLogFiles int
+}
+
+type Status struct {
+	Name      string
+	State     State
+	PID       int
+	StartedAt time.Time
+	Restarts  int
+	// exit code and error of the last run
+	ExitCode  int
+	LastError string
+	LogFile   string
+}
+
+type service struct {
+	conf    ServiceConfig
+	cancel  context.CancelFunc
+	done    chan struct{}
+	pidFile string
+	log     *rotatingFile
+
+	lock   sync.Mutex
+	status Status
+}
+
+type Manager struct {
+	dir      string
+	lock     sync.Mutex
+	services map[string]*service
+}
+
+// New creates a Manager that keeps the PID files and logs of the services in dir.
+func New(dir string) *Manager {
+	return &Manager{
+		dir:      dir,
+		services: make(map[string]*service),
+	}
+}
+
+// Start starts the service in the background. It fails when a service with the
+// same name is still running.
+func (p *Manager) Start(conf ServiceConfig) error {
+	if conf.Tool == nil {
+		return fmt.Errorf("no tool set for service %s", conf.Name)
+	}
+	if conf.Name == "" {
+		conf.Name = conf.Tool.GetMetadata().Name
+	}
+	if conf.Restart == "" {
+		conf.Restart = RestartNever
+	}
+	if conf.RestartDelay <= 0 {
+		conf.RestartDelay = defaultRestartDelay
+	}
+	if conf.LogFile == "" {
+		conf.LogFile = filepath.Join(p.dir, conf.Name+".log")
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if s, ok := p.services[conf.Name]; ok && !s.isDone() {
+		return fmt.Errorf("service %s is already running", conf.Name)
+	}
+
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return err
+	}
+	log, err := openRotatingFile(conf.LogFile, conf.MaxLogSize, conf.MaxLogFiles)
+	if err != nil {
+		return fmt.Errorf("failed to open log of service %s: %w", conf.Name, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &service{
+		conf:    conf,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		pidFile: filepath.Join(p.dir, conf.Name+".pid"),
+		log:     log,
+		status: Status{
+			Name:    conf.Name,
+			State:   StateRunning,
+			LogFile: conf.LogFile,
+		},
+	}
+	p.services[conf.Name] = s
+	go s.run(ctx)
+	return nil
+}
+
+// Stop stops the service and waits for it to exit. The process gets SIGTERM
+// first, see tools.TerminateGracePeriod.
+func (p *Manager) Stop(name string) error {
+	s, err := p.get(name)
+	if err != nil {
+		return err
+	}
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// Restart stops the service, if it is running, and starts it again with the
+// same config. The restart count is reset.
+func (p *Manager) Restart(name string) error {
+	s, err := p.get(name)
+	if err != nil {
+		return err
+	}
+	s.cancel()
+	<-s.done
+	return p.Start(s.conf)
+}
+
+func (p *Manager) Status(name string) (Status, error) {
+	s, err := p.get(name)
+	if err != nil {
+		return Status{}, err
+	}
+	return s.getStatus(), nil
+}
+
+// List returns the status of every service, sorted by name.
+func (p *Manager) List() (result []Status) {
+	p.lock.Lock()
+	for _, s := range p.services {
+		result = append(result, s.getStatus())
+	}
+	p.lock.Unlock()
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return
+}
+
+// StopAll stops every service, e.g. before the host program exits.
+func (p *Manager) StopAll() {
+	p.lock.Lock()
+	services := make([]*service, 0, len(p.services))
+	for _, s := range p.services {
+		services = append(services, s)
+	}
+	p.lock.Unlock()
+	for _, s := range services {
+		s.cancel()
+	}
+	for _, s := range services {
+		<-s.done
+	}
+}
+
+func (p *Manager) get(name string) (*service, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	s, ok := p.services[name]
+	if !ok {
+		return nil, fmt.Errorf("service %s not found", name)
+	}
+	return s, nil
+}
+
+func (p *service) isDone() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *service) getStatus() Status {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.status
+}
+
+func (p *service) update(f func(status *Status)) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	f(&p.status)
+}
+
+func (p *service) run(ctx context.Context) {
+	defer close(p.done)
+	defer p.log.Close()
+
+	for {
+		exitCode, err := p.runOnce(ctx)
+		p.update(func(status *Status) {
+			status.PID = 0
+			status.ExitCode = exitCode
+			status.LastError = ""
+			// stopped by Stop
+			if err != nil && ctx.Err() == nil {
+				status.LastError = err.Error()
+			}
+		})
+
+		if ctx.Err() != nil {
+			p.update(func(status *Status) { status.State = StateStopped })
+			return
+		}
+		if !p.shouldRestart(err) {
+			p.update(func(status *Status) { status.State = StateExited })
+			return
+		}
+		p.update(func(status *Status) {
+			status.State = StateRestarting
+			status.Restarts++
+		})
+		select {
+		case <-ctx.Done():
+			p.update(func(status *Status) { status.State = StateStopped })
+			return
+		case <-time.After(p.conf.RestartDelay):
+		}
+	}
+}
+
+func (p *service) shouldRestart(err error) bool {
+	switch p.conf.Restart {
+	case RestartAlways:
+	case RestartOnFailure:
+		if err == nil {
+			return false
+		}
+	default:
+		return false
+	}
+	return p.conf.MaxRestarts <= 0 || p.getStatus().Restarts < p.conf.MaxRestarts
+}
+
+func (p *service) runOnce(ctx context.Context) (exitCode int, err error) {
+	stream, err := p.conf.Tool.ExecuteStreamContext(ctx, p.conf.Options, p.conf.Args...)
+	if err != nil {
+		fmt.Fprintf(p.log, "[procmanager] failed to start %s: %v\n", p.conf.Name, err)
+		return -1, err
+	}
+	p.update(func(status *Status) {
+		status.State = StateRunning
+		status.PID = stream.Pid
+		status.StartedAt = time.Now()
+	})
+	os.WriteFile(p.pidFile, []byte(strconv.Itoa(stream.Pid)+"\n"), 0644)
+	defer os.Remove(p.pidFile)
+
+	var wg sync.WaitGroup
+	for _, output := range []io.ReadCloser{stream.Stdout, stream.Stderr} {
+		wg.Add(1)
+		go func(output io.ReadCloser) {
+			defer wg.Done()
+			defer output.Close()
+			io.Copy(p.log, output)
+		}(output)
+	}
+	wg.Wait()
+	exitCode, err = stream.Wait()
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(p.log, "[procmanager] %s exited: %v\n", p.conf.Name, err)
+	}
+	return
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"io"
+	"time"
+)
+
+const (
+	PhaseDownloading = "downloading"
+	PhaseVerifying   = "verifying"
+	PhaseExtracting  = "extracting"
+	PhaseCompleted   = "completed"
+)
+
+const progressReportInterval = 200 * time.Millisecond
+
+type DownloadProgress struct {
+	ToolName string
+	Version  string
+	Phase    string
+	// URL is the download url currently used, empty for local artifacts.
+	URL             string
+	DownloadedBytes int64
+	// TotalBytes is -1 when the server does not send a Content-Length.
+	TotalBytes int64
+	// Speed is the average download speed in bytes per second.
+	Speed float64
+}
+
+type ProgressCallback func(progress DownloadProgress)
+
+// progressReader 包装下载的 body，按固定间隔回调下载进度
+type progressReader struct {
+	reader     io.Reader
+	progress   DownloadProgress
+	callback   ProgressCallback
+	startedAt  time.Time
+	lastReport time.Time
+}
+
+func newProgressReader(reader io.Reader, progress DownloadProgress, callback ProgressCallback) *progressReader {
+	now := time.Now()
+	return &progressReader{
+		reader:    reader,
+		progress:  progress,
+		callback:  callback,
+		startedAt: now,
+	}
+}
+
+func (p *progressReader) Read(b []byte) (n int, err error) {
+	n, err = p.reader.Read(b)
+	p.progress.DownloadedBytes += int64(n)
+	if p.callback != nil && (err != nil || time.Since(p.lastReport) >= progressReportInterval) {
+		p.report()
+	}
+	return
+}
+
+func (p *progressReader) report() {
+	p.lastReport = time.Now()
+	if elapsed := p.lastReport.Sub(p.startedAt).Seconds(); elapsed > 0 {
+		p.progress.Speed = float64(p.progress.DownloadedBytes) / elapsed
+	}
+	p.callback(p.progress)
+}
+
+func reportPhase(callback ProgressCallback, progress DownloadProgress, phase string) {
+	if callback == nil {
+		return
+	}
+	progress.Phase = phase
+	callback(progress)
+}
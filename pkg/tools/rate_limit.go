@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// 令牌桶最少允许的突发量，避免限速很低时每次只能读几个字节
+const minRateLimitBurst = 1024
+
+// rateLimiter 是一个简单的令牌桶，可以被多个下载共享
+type rateLimiter struct {
+	lock   sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	l := &rateLimiter{}
+	l.setRate(bytesPerSec)
+	return l
+}
+
+func (l *rateLimiter) setRate(bytesPerSec int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.rate = float64(bytesPerSec)
+	l.tokens = 0
+	l.last = time.Now()
+}
+
+func (l *rateLimiter) burst() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	if l.rate < minRateLimitBurst {
+		return minRateLimitBurst
+	}
+	return int(l.rate)
+}
+
+// wait 消耗 n 个令牌，令牌不足时等待到补足为止
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.lock.Lock()
+	if l.rate <= 0 {
+		l.lock.Unlock()
+		return nil
+	}
+	now := time.Now()
+	burst := float64(minRateLimitBurst)
+	if l.rate > burst {
+		burst = l.rate
+	}
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.lock.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type rateLimitedReader struct {
+	ctx      context.Context
+	reader   io.Reader
+	limiters []*rateLimiter
+}
+
+func (p *rateLimitedReader) Read(b []byte) (n int, err error) {
+	for _, l := range p.limiters {
+		if burst := l.burst(); burst > 0 && len(b) > burst {
+			b = b[:burst]
+		}
+	}
+	n, err = p.reader.Read(b)
+	for _, l := range p.limiters {
+		if waitErr := l.wait(p.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return
+}
+
+// 所有下载共享的限速，见 API.SetDownloadRateLimit
+var downloadRateLimiter = newRateLimiter(0)
+
+// SetDownloadRateLimit limits the total bandwidth used by all downloads, in
+// bytes per second. Zero or less removes the limit. Tools can also be limited
+// on their own with the downloadRateLimit config field.
+func (p *API) SetDownloadRateLimit(bytesPerSec int64) {
+	downloadRateLimiter.setRate(bytesPerSec)
+}
+
+// limitDownloadRate 用全局限速和 toolBytesPerSec（大于 0 时）包装下载的 body
+func limitDownloadRate(ctx context.Context, r io.Reader, toolBytesPerSec int64) io.Reader {
+	limiters := []*rateLimiter{downloadRateLimiter}
+	if toolBytesPerSec > 0 {
+		limiters = append(limiters, newRateLimiter(toolBytesPerSec))
+	}
+	return &rateLimitedReader{ctx: ctx, reader: r, limiters: limiters}
+}
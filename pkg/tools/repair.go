@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// RepairResult 描述一次 Repair 做了什么
+type RepairResult struct {
+	// files that did not match the manifest
+	Problems []FileProblem `json:"problems"`
+	// the whole tool folder was installed again instead of replacing the files
+	Reinstalled bool `json:"reinstalled"`
+}
+
+// 安装包中没有与清单一致的文件，比如文件由 postInstall 生成
+var errNotInArtifact = errors.New("file does not match the artifact")
+
+func (p *DownloadedTool) Repair() (RepairResult, error) {
+	return p.RepairWithProgress(context.Background(), nil)
+}
+
+// RepairWithProgress checks the tool with its manifest, see API.Verify, and
+// replaces the missing and modified files with the ones from a fresh download
+// of the artifact. The tool is installed again as a whole when that is not
+// possible: for git sources, installs without a manifest, or files that are
+// not in the artifact, e.g. written by postInstall. A tool that is not
+// installed at all is installed.
+func (p *DownloadedTool) RepairWithProgress(ctx context.Context, callback ProgressCallback) (result RepairResult, err error) {
+	lock := lockInstall(p.ToolName + "@" + p.Version)
+	defer lock.Unlock()
+
+	if err = p.checkWritable(); err != nil {
+		return
+	}
+	toolFolder := filepath.FromSlash(p.GetToolFolder())
+	if hasCompleteMarker(toolFolder) {
+		manifest, manifestErr := readManifest(toolFolder)
+		if manifestErr == nil {
+			result.Problems = checkManifest(toolFolder, manifest, true)
+			if len(result.Problems) == 0 {
+				return
+			}
+			if err = evictModifiedFiles(toolFolder, manifest, result.Problems); err != nil {
+				return
+			}
+			if p.Source != SourceGit {
+				err = p.repairFiles(ctx, callback, toolFolder, manifest, result.Problems)
+				if !errors.Is(err, errNotInArtifact) {
+					return
+				}
+				log.Printf("failed to repair tool %s %s, installing it again: %v\n", p.ToolName, p.Version, err)
+			}
+		}
+	}
+
+	result.Reinstalled = true
+	if err = p.reinstall(ctx, callback); err == nil {
+		pruneContentStore(rootOfToolFolder(toolFolder))
+	}
+	return
+}
+
+// evictModifiedFiles 被修改的文件可能通过硬链接改坏了内容存储中的副本，
+// 把这些副本移出存储，之后去重时不会再链接到它们
+func evictModifiedFiles(toolFolder string, manifest *Manifest, problems []FileProblem) error {
+	expected := make(map[string]string)
+	for _, entry := range manifest.Files {
+		expected[entry.Path] = entry.SHA256
+	}
+	store := contentStore(rootOfToolFolder(toolFolder))
+	for _, problem := range problems {
+		if problem.Problem != FileModified {
+			continue
+		}
+		path := filepath.Join(toolFolder, filepath.FromSlash(problem.Path))
+		if err := store.Evict(expected[problem.Path], path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repairFiles 用重新下载的安装包中的文件替换损坏的文件，只要有一个文件无法修复就不做任何替换
+func (p *DownloadedTool) repairFiles(ctx context.Context, callback ProgressCallback, toolFolder string, manifest *Manifest, problems []FileProblem) error {
+	expected := make(map[string]ManifestEntry)
+	for _, entry := range manifest.Files {
+		expected[entry.Path] = entry
+	}
+	return p.fetchArtifact(ctx, callback, func(filePath, source string, progress DownloadProgress) error {
+		if err := p.verifyArtifact(ctx, filePath, progress, callback); err != nil {
+			return err
+		}
+		reportPhase(callback, progress, PhaseExtracting)
+		// 解压到下载所在的 tmp 目录，随它一起删除
+		extracted, err := os.MkdirTemp(filepath.Dir(filePath), "repair_")
+		if err != nil {
+			return err
+		}
+		if err = p.extractArtifact(filePath, extracted); err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			src := filepath.Join(extracted, filepath.FromSlash(problem.Path))
+			if sum, err := fileSHA256(src); err != nil || sum != expected[problem.Path].SHA256 {
+				return fmt.Errorf("%w: %s", errNotInArtifact, problem.Path)
+			}
+		}
+
+		for _, problem := range problems {
+			src := filepath.Join(extracted, filepath.FromSlash(problem.Path))
+			dst := filepath.Join(toolFolder, filepath.FromSlash(problem.Path))
+			if err := replaceFile(src, dst); err != nil {
+				return fmt.Errorf("failed to repair %s: %w", problem.Path, err)
+			}
+		}
+		dedupeToolFolder(toolFolder)
+		reportPhase(callback, progress, PhaseCompleted)
+		return nil
+	})
+}
+
+// replaceFile 先复制到 dst 旁边再改名覆盖，dst 是硬链接时不会改动其他链接的内容
+func replaceFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp := dst + ".rt-repair"
+	if err = copyFile(src, tmp, info.Mode()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err = os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Repair repairs an installed tool, see DownloadedTool.RepairWithProgress.
+func (p *API) Repair(ctx context.Context, tool Tool, callback ProgressCallback) (result RepairResult, err error) {
+	downloaded, ok := tool.(*DownloadedTool)
+	if !ok {
+		return result, fmt.Errorf("tool %s uses a dev override and cannot be repaired", tool.GetMetadata().Name)
+	}
+	return downloaded.RepairWithProgress(ctx, callback)
+}
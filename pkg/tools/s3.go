@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3Scheme 是 downloadUrl 中 S3 对象的前缀，格式为 s3://bucket/key
+const S3Scheme = "s3://"
+
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Config 是访问 s3:// 地址时使用的凭据和服务地址，未设置的字段使用标准的 AWS 环境变量：
+// AWS_ACCESS_KEY_ID、AWS_SECRET_ACCESS_KEY、AWS_SESSION_TOKEN、AWS_REGION（或
+// AWS_DEFAULT_REGION）和 AWS_ENDPOINT_URL。没有凭据时以匿名方式访问
+type S3Config struct {
+	// e.g. http://minio.local:9000, the AWS endpoint of Region when empty.
+	// Buckets are addressed path style on custom endpoints.
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+var (
+	s3ConfigLock sync.RWMutex
+	s3Config     S3Config
+)
+
+// SetS3Config sets the credentials and endpoint used for s3:// download URLs.
+func SetS3Config(conf S3Config) {
+	s3ConfigLock.Lock()
+	defer s3ConfigLock.Unlock()
+	s3Config = conf
+}
+
+// SetS3Config sets the config used for s3:// download URLs, see the package level SetS3Config.
+func (p *API) SetS3Config(conf S3Config) {
+	SetS3Config(conf)
+}
+
+// 合并设置的配置与环境变量
+func resolveS3Config() S3Config {
+	s3ConfigLock.RLock()
+	conf := s3Config
+	s3ConfigLock.RUnlock()
+
+	fallback := func(value *string, envNames ...string) {
+		for _, name := range envNames {
+			if *value != "" {
+				return
+			}
+			*value = os.Getenv(name)
+		}
+	}
+	fallback(&conf.Endpoint, "AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL")
+	fallback(&conf.Region, "AWS_REGION", "AWS_DEFAULT_REGION")
+	if conf.AccessKeyID == "" && conf.SecretAccessKey == "" {
+		conf.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		conf.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		fallback(&conf.SessionToken, "AWS_SESSION_TOKEN")
+	}
+	if conf.Region == "" {
+		conf.Region = "us-east-1"
+	}
+	return conf
+}
+
+// resolveS3Object 把 s3://bucket/key 转为 HTTP 地址，有凭据时附带 SigV4 签名
+func resolveS3Object(rawURL string) (request downloadRequest, err error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(rawURL, S3Scheme), "/")
+	if !ok || bucket == "" || key == "" {
+		return request, fmt.Errorf("invalid s3 url %s, expected s3://bucket/key", rawURL)
+	}
+
+	conf := resolveS3Config()
+	var objectURL *url.URL
+	if conf.Endpoint != "" {
+		if objectURL, err = url.Parse(strings.TrimSuffix(conf.Endpoint, "/")); err != nil {
+			return request, fmt.Errorf("invalid s3 endpoint %s: %w", conf.Endpoint, err)
+		}
+		objectURL.Path += "/" + bucket + "/" + key
+	} else {
+		objectURL = &url.URL{
+			Scheme: "https",
+			Host:   fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, conf.Region),
+			Path:   "/" + key,
+		}
+	}
+	objectURL.RawPath = s3EscapePath(objectURL.Path)
+
+	request = downloadRequest{
+		URL:      objectURL.String(),
+		FileName: path.Base(key),
+	}
+	if conf.AccessKeyID != "" && conf.SecretAccessKey != "" {
+		request.Header = signS3Request(conf, objectURL, time.Now().UTC())
+	}
+	return request, nil
+}
+
+// signS3Request returns the headers of a GET request signed with AWS Signature
+// Version 4. The payload is not signed, as a GET has none.
+func signS3Request(conf S3Config, u *url.URL, now time.Time) http.Header {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	header := http.Header{
+		"X-Amz-Date":           {amzDate},
+		"X-Amz-Content-Sha256": {s3UnsignedPayload},
+	}
+	if conf.SessionToken != "" {
+		header.Set("X-Amz-Security-Token", conf.SessionToken)
+	}
+
+	signed := map[string]string{"host": u.Host}
+	for name, values := range header {
+		signed[strings.ToLower(name)] = values[0]
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(signed[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		u.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+	scope := date + "/" + conf.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+conf.SecretAccessKey), date)
+	for _, part := range []string{conf.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		conf.AccessKeyID, scope, signedHeaders, signature))
+	return header
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3EscapePath 按 SigV4 的要求编码路径，除 unreserved 字符和 / 之外都转义
+func s3EscapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// Scope 决定工具安装到哪个根目录
+type Scope string
+
+const (
+	// ScopeDefault installs into the folder set by SetToolFolder.
+	ScopeDefault Scope = ""
+	ScopeUser    Scope = "user"
+	ScopeSystem  Scope = "system"
+)
+
+var ErrScopeNotWritable = errors.New("install scope is not writable")
+
+var (
+	scopeFoldersLock sync.RWMutex
+	scopeFolders     = map[Scope]string{}
+)
+
+// SetScopeFolder overrides the root folder used for a scope.
+func SetScopeFolder(scope Scope, folder string) {
+	scopeFoldersLock.Lock()
+	defer scopeFoldersLock.Unlock()
+	scopeFolders[scope] = folder
+}
+
+func GetScopeFolder(scope Scope) (string, error) {
+	if scope == ScopeDefault {
+		return GetToolFolder(), nil
+	}
+
+	scopeFoldersLock.RLock()
+	folder, ok := scopeFolders[scope]
+	scopeFoldersLock.RUnlock()
+	if ok {
+		return folder, nil
+	}
+
+	switch scope {
+	case ScopeUser:
+		return defaultDataFolder()
+	case ScopeSystem:
+		return defaultSystemScopeFolder(), nil
+	default:
+		return "", fmt.Errorf("unknown install scope: %s", scope)
+	}
+}
+
+func defaultSystemScopeFolder() string {
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, "remotetools")
+	}
+	return "/usr/local/lib/remotetools"
+}
+
+// checkScopeWritable 确认可以在 scope 的根目录下创建文件，权限不足时返回 ErrScopeNotWritable
+func checkScopeWritable(scope Scope, folder string) error {
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("%w: %s (%s): %v", ErrScopeNotWritable, scope, folder, err)
+		}
+		return err
+	}
+	f, err := os.CreateTemp(folder, ".write_test_")
+	if err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("%w: %s (%s): %v", ErrScopeNotWritable, scope, folder, err)
+		}
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/config"
+	"github.com/kira1928/remotetools/pkg/tools/verify"
+	"github.com/kira1928/remotetools/pkg/version"
+)
+
+// SelfUpdateRepo 是发布 remotetools 的 GitHub 仓库
+const SelfUpdateRepo = "kira1928/remotetools"
+
+// SelfUpdateChecksums 是 release 中列出各二进制 sha256 的文件，它的签名为同名加
+// .minisig、.sig 或 .asc 后缀的文件
+const SelfUpdateChecksums = "checksums.txt"
+
+type SelfUpdateOptions struct {
+	// kira1928/remotetools by default
+	Repo string
+	// minisign or gpg public key the checksums file is signed with, see
+	// version.PublicKey
+	PublicKey string
+	// the binary to replace, the running executable by default
+	Executable string
+	// CheckOnly only looks up the latest release.
+	CheckOnly bool
+}
+
+type SelfUpdateResult struct {
+	CurrentVersion string
+	LatestVersion  string
+	// the binary was replaced, or would be without CheckOnly
+	Updated bool
+}
+
+// SelfUpdateAssetName returns the name of the release asset of the CLI for a
+// platform, remotetools_<os>_<arch> with .exe on windows.
+func SelfUpdateAssetName(goos, goarch string) string {
+	name := "remotetools_" + goos + "_" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// SelfUpdate replaces the CLI binary with the one from the latest GitHub release
+// when it is newer than version.Version. The binary has to match the sha256 in
+// the checksums file, whose signature is verified with the public key first. The
+// new binary is written next to the old one and renamed over it, so the old
+// binary stays in place when anything fails.
+func SelfUpdate(ctx context.Context, opts SelfUpdateOptions) (result SelfUpdateResult, err error) {
+	result.CurrentVersion = version.Version
+	repo := opts.Repo
+	if repo == "" {
+		repo = SelfUpdateRepo
+	}
+	executable := opts.Executable
+	if executable == "" {
+		if executable, err = os.Executable(); err != nil {
+			return
+		}
+		if executable, err = filepath.EvalSymlinks(executable); err != nil {
+			return
+		}
+	}
+	// left behind by the previous update on windows
+	os.Remove(executable + ".old")
+
+	token := os.Getenv("GITHUB_TOKEN")
+	release, err := fetchGitHubRelease(ctx, repo, "", token)
+	if err != nil {
+		return result, fmt.Errorf("failed to get the latest release of %s: %w", repo, err)
+	}
+	result.LatestVersion = release.TagName
+	if config.CompareVersions(strings.TrimPrefix(release.TagName, "v"), strings.TrimPrefix(version.Version, "v")) <= 0 {
+		return
+	}
+	result.Updated = true
+	if opts.CheckOnly {
+		return
+	}
+	if strings.TrimSpace(opts.PublicKey) == "" {
+		return result, fmt.Errorf("no public key to verify the release with")
+	}
+
+	assets := make(map[string]githubAsset)
+	for _, asset := range release.Assets {
+		assets[asset.Name] = asset
+	}
+	download := func(name string) (*http.Response, error) {
+		asset, ok := assets[name]
+		if !ok {
+			return nil, fmt.Errorf("asset %s not found in release %s", name, release.TagName)
+		}
+		if token == "" {
+			return openRemoteDownload(ctx, asset.BrowserDownloadURL, nil)
+		}
+		return openRemoteDownload(ctx, asset.URL, http.Header{
+			"Accept":        {"application/octet-stream"},
+			"Authorization": {"Bearer " + token},
+		})
+	}
+	readAsset := func(name string) ([]byte, error) {
+		resp, err := download(name)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return io.ReadAll(io.LimitReader(resp.Body, maxChecksumFileSize))
+	}
+
+	dir := filepath.Dir(executable)
+	stagingFolder, err := os.MkdirTemp(dir, ".remotetools-update-")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(stagingFolder)
+
+	// the checksums file and its signature
+	checksums, err := readAsset(SelfUpdateChecksums)
+	if err != nil {
+		return result, err
+	}
+	var signature []byte
+	for _, suffix := range []string{".minisig", ".sig", ".asc"} {
+		if _, ok := assets[SelfUpdateChecksums+suffix]; ok {
+			if signature, err = readAsset(SelfUpdateChecksums + suffix); err != nil {
+				return result, err
+			}
+			break
+		}
+	}
+	if signature == nil {
+		return result, fmt.Errorf("no signature of %s in release %s", SelfUpdateChecksums, release.TagName)
+	}
+	checksumsPath := filepath.Join(stagingFolder, SelfUpdateChecksums)
+	if err = os.WriteFile(checksumsPath, checksums, 0644); err != nil {
+		return result, err
+	}
+	if err = verify.File("", opts.PublicKey, checksumsPath, signature); err != nil {
+		return result, fmt.Errorf("failed to verify the signature of %s: %w", SelfUpdateChecksums, err)
+	}
+
+	// the binary
+	assetName := SelfUpdateAssetName(runtime.GOOS, runtime.GOARCH)
+	expected, err := parseChecksumFile(checksums, assetName)
+	if err != nil {
+		return result, err
+	}
+	resp, err := download(assetName)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	binaryPath := filepath.Join(stagingFolder, assetName)
+	if _, err = writeDownload(resp.Body, binaryPath, DownloadProgress{}, nil); err != nil {
+		return result, err
+	}
+	if err = verifySHA256(binaryPath, expected); err != nil {
+		return result, fmt.Errorf("failed to verify %s: %w", assetName, err)
+	}
+	if err = os.Chmod(binaryPath, 0755); err != nil {
+		return result, err
+	}
+
+	// windows does not allow replacing a running executable, but renaming it
+	if runtime.GOOS == "windows" {
+		if err = os.Rename(executable, executable+".old"); err != nil {
+			return result, err
+		}
+		if err = os.Rename(binaryPath, executable); err != nil {
+			os.Rename(executable+".old", executable)
+			return result, err
+		}
+		return result, nil
+	}
+	return result, os.Rename(binaryPath, executable)
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"log"
+	"path/filepath"
+	"strconv"
+
+	"github.com/kira1928/remotetools/pkg/settings"
+)
+
+// ApplySettings sets the package folders, the download proxy and the install
+// options (offline mode, size limit, dedupe, strict checks) from the resolved
+// settings. Options without a value keep their current value.
+func ApplySettings(r *settings.Resolver) {
+	if folder := r.Get(settings.KeyRoot); folder != "" {
+		SetToolFolder(folder)
+	}
+	if folder := r.Get(settings.KeyCache); folder != "" {
+		SetCacheFolder(folder)
+	}
+	if folder := r.Get(settings.KeyTmp); folder != "" {
+		SetTmpFolder(folder)
+	}
+	if folder := r.Get(settings.KeyUserRoot); folder != "" {
+		SetScopeFolder(ScopeUser, folder)
+	}
+	if folder := r.Get(settings.KeySystemRoot); folder != "" {
+		SetScopeFolder(ScopeSystem, folder)
+	}
+	if proxy := r.Get(settings.KeyProxy); proxy != "" {
+		if err := SetProxy(proxy); err != nil {
+			log.Println(err)
+		}
+	}
+	if offline := r.Get(settings.KeyOffline); offline != "" {
+		enabled, err := strconv.ParseBool(offline)
+		if err != nil {
+			log.Printf("invalid offline setting %q, expected true or false\n", offline)
+		} else {
+			SetOfflineMode(enabled)
+		}
+	}
+	if maxSize := r.Get(settings.KeyMaxSize); maxSize != "" {
+		if size, err := ParseSize(maxSize); err != nil {
+			log.Println(err)
+		} else {
+			SetMaxToolFolderSize(size)
+		}
+	}
+	if dedupe := r.Get(settings.KeyDedupe); dedupe != "" {
+		enabled, err := strconv.ParseBool(dedupe)
+		if err != nil {
+			log.Printf("invalid dedupe setting %q, expected true or false\n", dedupe)
+		} else {
+			SetDedupe(enabled)
+		}
+	}
+	if strict := r.Get(settings.KeyStrictCheck); strict != "" {
+		enabled, err := strconv.ParseBool(strict)
+		if err != nil {
+			log.Printf("invalid strictCheck setting %q, expected true or false\n", strict)
+		} else {
+			SetStrictInstallCheck(enabled)
+		}
+	}
+
+	// project mode takes precedence over the root folder
+	switch mode := r.Get(settings.KeyInstallMode); mode {
+	case "", InstallModeGlobal:
+	case InstallModeProject:
+		SetToolFolder(filepath.Join(ProjectFolder("."), ProjectToolFolder))
+	default:
+		log.Printf("unknown install mode %q, using %q\n", mode, InstallModeGlobal)
+	}
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// CreateShims writes a launcher script for every installed executable tool
+// into dir, named after the tool: a .cmd file on Windows and a shell script
+// elsewhere. With dir in PATH the tools can be run from a terminal. A shim
+// starts the version GetTool returns, or the highest installed version when
+// that one is not installed, and sets the env of the tool config. It returns
+// the paths of the created shims.
+func (p *API) CreateShims(dir string) (shims []string, err error) {
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	for _, toolName := range p.ToolNames() {
+		tool, err := p.shimTool(toolName)
+		if err != nil {
+			return shims, err
+		}
+		if tool == nil || !tool.IsExecutable() {
+			continue
+		}
+		shimPath, err := writeShim(dir, toolName, tool)
+		if err != nil {
+			return shims, fmt.Errorf("failed to create shim of tool %s: %w", toolName, err)
+		}
+		shims = append(shims, shimPath)
+	}
+	return
+}
+
+// 返回 shim 指向的已安装版本，没有安装任何版本时返回 nil
+func (p *API) shimTool(toolName string) (Tool, error) {
+	tool, err := p.GetTool(toolName)
+	if err != nil {
+		return nil, err
+	}
+	if tool != nil && tool.DoesToolExist() {
+		return tool, nil
+	}
+	installed, err := p.GetInstalledVersions(toolName)
+	if err != nil || len(installed) == 0 {
+		return nil, nil
+	}
+	latest := installed[0]
+	for _, version := range installed[1:] {
+		if config.CompareVersions(version, latest) > 0 {
+			latest = version
+		}
+	}
+	return p.GetToolWithVersion(toolName, latest)
+}
+
+func writeShim(dir, toolName string, tool Tool) (string, error) {
+	// 工具名来自配置，不能借 ../ 等写到 dir 之外
+	if !isBundlePathElement(toolName) {
+		return "", fmt.Errorf("invalid tool name %q for a shim", toolName)
+	}
+	toolPath, err := filepath.Abs(filepath.FromSlash(tool.GetToolPath()))
+	if err != nil {
+		return "", err
+	}
+	var env []string
+	if withEnv, ok := tool.(interface{ getToolEnv() []string }); ok {
+		env = withEnv.getToolEnv()
+	}
+
+	var shimPath, content string
+	if runtime.GOOS == "windows" {
+		shimPath = filepath.Join(dir, toolName+".cmd")
+		lines := []string{"@echo off", "rem generated by remotetools, runs " + toolName + " " + tool.GetVersion(), "setlocal"}
+		for _, kv := range env {
+			lines = append(lines, fmt.Sprintf(`set "%s"`, cmdEscape(kv)))
+		}
+		lines = append(lines, fmt.Sprintf(`"%s" %%*`, cmdEscape(toolPath)), "exit /b %ERRORLEVEL%")
+		content = strings.Join(lines, "\r\n") + "\r\n"
+	} else {
+		shimPath = filepath.Join(dir, toolName)
+		lines := []string{"#!/bin/sh", "# generated by remotetools, runs " + toolName + " " + tool.GetVersion()}
+		for _, kv := range env {
+			name, value, _ := strings.Cut(kv, "=")
+			lines = append(lines, "export "+name+"="+shellQuote(value))
+		}
+		lines = append(lines, "exec "+shellQuote(toolPath)+` "$@"`)
+		content = strings.Join(lines, "\n") + "\n"
+	}
+	if err = os.WriteFile(shimPath, []byte(content), 0755); err != nil {
+		return "", err
+	}
+	// WriteFile keeps the mode of an existing file
+	return shimPath, os.Chmod(shimPath, 0755)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// cmdEscape 转义 .cmd 文件中双引号内的值，cmd 会展开其中的 %NAME%
+func cmdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
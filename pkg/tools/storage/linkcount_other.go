@@ -0,0 +1,22 @@
+//go:build !windows
+
+package storage
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// LinkCount returns the number of hard links to the file at path.
+func LinkCount(path string) (uint64, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("no link count for %s", path)
+	}
+	return uint64(stat.Nlink), nil
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package storage
+
+import "syscall"
+
+// LinkCount returns the number of hard links to the file at path.
+func LinkCount(path string) (uint64, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	handle, err := syscall.CreateFile(pathPtr, 0,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.CloseHandle(handle)
+	var data syscall.ByHandleFileInformation
+	if err = syscall.GetFileInformationByHandle(handle, &data); err != nil {
+		return 0, err
+	}
+	return uint64(data.NumberOfLinks), nil
+}
@@ -0,0 +1,204 @@
+// Package storage deduplicates the files of installed tools.
+//
+// A Store keeps one copy of every file content, addressed by its sha256 and
+// permission bits, as blobs/<aa>/<sha256>-<perm>. Dedupe replaces the files of
+// a folder with hard links to the blobs, so identical files of different tools
+// and versions take the disk space only once. The store has to be on the same
+// file system as the folders, hard links cannot cross file systems.
+//
+// Linked files share their data: a tool that modifies one of its files in
+// place changes it for every folder linking the same blob.
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const blobsFolder = "blobs"
+
+type Store struct {
+	root string
+}
+
+// Stats 统计一次 Dedupe 的结果
+type Stats struct {
+	// regular files looked at
+	Files int `json:"files"`
+	// files replaced with a link to a blob another file already added
+	Linked int `json:"linked"`
+	// bytes freed by Linked
+	SavedBytes int64 `json:"savedBytes"`
+}
+
+func (s *Stats) Add(other Stats) {
+	s.Files += other.Files
+	s.Linked += other.Linked
+	s.SavedBytes += other.SavedBytes
+}
+
+func New(root string) *Store {
+	return &Store{root: root}
+}
+
+func (s *Store) Root() string {
+	return s.root
+}
+
+func (s *Store) blobPath(sum string, perm fs.FileMode) string {
+	return filepath.Join(s.root, blobsFolder, sum[:2], fmt.Sprintf("%s-%o", sum, perm))
+}
+
+// Dedupe links every regular file of folder to its blob, adding the blobs that
+// do not exist yet. skip, if not nil, is called with the slash separated path
+// relative to folder and excludes files whose content must not be shared.
+func (s *Store) Dedupe(folder string, skip func(rel string) bool) (stats Stats, err error) {
+	err = filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if skip != nil {
+			rel, err := filepath.Rel(folder, path)
+			if err != nil {
+				return err
+			}
+			if skip(filepath.ToSlash(rel)) {
+				return nil
+			}
+		}
+		stats.Files++
+		linked, err := s.linkFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to dedupe %s: %w", path, err)
+		}
+		if linked {
+			stats.Linked++
+			if info, err := d.Info(); err == nil {
+				stats.SavedBytes += info.Size()
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// linkFile 把 path 替换为指向 blob 的硬链接，blob 不存在或内容与其哈希不符时 path 自身成为 blob。
+// 只有原本独立的文件被替换时返回 true
+func (s *Store) linkFile(path string) (linked bool, err error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return
+	}
+	blob := s.blobPath(sum, info.Mode().Perm())
+	blobInfo, err := os.Lstat(blob)
+	if err == nil && !os.SameFile(info, blobInfo) {
+		// 被就地修改过的 blob 不再可信，移除后由 path 取代，已链接到它的文件不受影响
+		var blobSum string
+		if blobSum, err = fileSHA256(blob); err != nil {
+			return
+		}
+		if blobSum != sum {
+			if err = os.Remove(blob); err != nil {
+				return
+			}
+			blobInfo, err = os.Lstat(blob)
+		}
+	}
+	if os.IsNotExist(err) {
+		if err = os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+			return
+		}
+		return false, os.Link(path, blob)
+	}
+	if err != nil {
+		return
+	}
+	if os.SameFile(info, blobInfo) {
+		return false, nil
+	}
+
+	// 先在旁边建立链接再改名覆盖，中途失败不会丢失文件
+	tmp := path + ".rt-link"
+	os.Remove(tmp)
+	if err = os.Link(blob, tmp); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	return true, nil
+}
+
+// Evict removes the blob of sum that path is linked to, e.g. after path was
+// modified in place, which changed the blob too. Files still linked to it
+// keep their data, only new files are no longer linked to it.
+func (s *Store) Evict(sum, path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	blob := s.blobPath(sum, info.Mode().Perm())
+	blobInfo, err := os.Lstat(blob)
+	if err != nil || !os.SameFile(info, blobInfo) {
+		return nil
+	}
+	return os.Remove(blob)
+}
+
+// Prune removes the blobs no folder links to any more and returns how many
+// were removed and the bytes freed.
+func (s *Store) Prune() (removed int, freed int64, err error) {
+	err = filepath.WalkDir(filepath.Join(s.root, blobsFolder), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		links, err := LinkCount(path)
+		if err != nil || links > 1 {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		freed += info.Size()
+		return nil
+	})
+	return
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return strings.ToLower(hex.EncodeToString(h.Sum(nil))), nil
+}
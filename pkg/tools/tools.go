@@ -1,29 +1,134 @@
 package tools
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/kira1928/remotetools/pkg/config"
+	"github.com/kira1928/remotetools/pkg/settings"
 )
 
 type Tool interface {
 	DoesToolExist() bool
 	Install() error
+	InstallContext(ctx context.Context) error
+	// InstallFromArchive installs from an already downloaded artifact instead of
+	// the download URL. The artifact is still verified before extraction.
+	InstallFromArchive(path string) error
 	Execute(args ...string) error
+	// ExecuteContext sends SIGTERM to the tool when ctx is done, and SIGKILL
+	// after TerminateGracePeriod.
+	ExecuteContext(ctx context.Context, args ...string) error
+	ExecuteWithTimeout(d time.Duration, args ...string) error
+	ExecuteStream(args ...string) (*ExecStream, error)
+	ExecuteStreamContext(ctx context.Context, opts ExecOptions, args ...string) (*ExecStream, error)
+	ExecuteStreamFunc(onStdout, onStderr OutputHandler, args ...string) (exitCode int, err error)
+	ExecuteWithOptions(opts ExecOptions, args ...string) error
 	CreateExecuteCmd(args ...string) (cmd *exec.Cmd, err error)
+	CreateExecuteCmdContext(ctx context.Context, args ...string) (cmd *exec.Cmd, err error)
+	CreateExecuteCmdWithOptions(opts ExecOptions, args ...string) (cmd *exec.Cmd, err error)
 	GetVersion() string
 	GetToolPath() string
+	IsExecutable() bool
+	// GetConfiguredEntry returns pathToEntry for the current platform.
+	GetConfiguredEntry() string
+	GetDownloadURLForPlatform(goos, goarch string) (string, error)
+	GetMetadata() ToolMetadata
+	// GetInstallRecord returns when, by whom and from where the tool was
+	// installed. It is nil for installs that predate install records.
+	GetInstallRecord() (*InstallRecord, error)
 }
 
-var toolFolder string = "external_tools"
+// ToolMetadata 是工具配置中与平台无关的信息，以及当前平台解析出的值
+type ToolMetadata struct {
+	Name         string
+	Version      string
+	Scope        string
+	Channel      string
+	Dependencies []string
+	DownloadURL  string
+	PathToEntry  string
+	IsExecutable bool
+	PrintInfoCmd []string
+	EnvWhitelist []string
+}
+
+func newToolMetadata(conf *config.ToolConfig) ToolMetadata {
+	return ToolMetadata{
+		Name:         conf.ToolName,
+		Version:      conf.Version,
+		Scope:        conf.Scope,
+		Channel:      conf.GetChannel(),
+		Dependencies: append([]string(nil), conf.Dependencies...),
+		DownloadURL:  conf.Expand(conf.DownloadURL.Value),
+		PathToEntry:  conf.Expand(conf.PathToEntry.Value),
+		IsExecutable: conf.IsExecutable(),
+		PrintInfoCmd: append([]string(nil), conf.PrintInfoCmd...),
+		EnvWhitelist: append([]string(nil), conf.EnvWhitelist...),
+	}
+}
+
+var (
+	toolFolder  string
+	cacheFolder string
+	tmpFolder   string
+)
 
 func SetToolFolder(folder string) {
 	toolFolder = folder
 }
 
+// GetToolFolder returns the folder set by SetToolFolder, or the platform data
+// folder (see defaultDataFolder) when none is set.
 func GetToolFolder() string {
-	return toolFolder
+	if toolFolder != "" {
+		return toolFolder
+	}
+	if folder, err := defaultDataFolder(); err == nil {
+		return folder
+	}
+	return "external_tools"
+}
+
+// SetCacheFolder sets the folder for per-user cached data, such as the
+// default tmp folder.
+func SetCacheFolder(folder string) {
+	cacheFolder = folder
+}
+
+// GetCacheFolder returns the folder set by SetCacheFolder, or the platform
+// cache folder (see defaultCacheFolder) when none is set.
+func GetCacheFolder() string {
+	if cacheFolder != "" {
+		return cacheFolder
+	}
+	if folder, err := defaultCacheFolder(); err == nil {
+		return folder
+	}
+	return filepath.Join(GetToolFolder(), ".cache")
+}
+
+func SetTmpFolder(folder string) {
+	tmpFolder = folder
+}
+
+// GetTmpFolder returns the folder downloads are written to before extraction.
+// It defaults to a tmp folder in the per-user cache folder, so users do not
+// share it.
+func GetTmpFolder() string {
+	if tmpFolder != "" {
+		return tmpFolder
+	}
+	return filepath.Join(GetCacheFolder(), "tmp")
 }
 
 var (
@@ -31,16 +136,150 @@ var (
 )
 
 type API struct {
+	lock        sync.RWMutex
+	configPaths []string
+	// layers added by MergeConfig, applied again when the files are reloaded
+	configLayers  []config.Config
 	config        config.Config
 	toolInstances map[string]Tool
+	// versions pinned by the project pin file, see LoadProjectPins
+	pins    map[string]string
+	pinPath string
+	// versions locked by LoadLockfile
+	locks    map[string]LockedTool
+	lockPath string
+
+	updateLock         sync.Mutex
+	updateChecker      *updateChecker
+	updateHandlers     []UpdateHandler
+	autoInstallUpdates bool
+	notifiedUpdates    map[string]string
+	autoUpdater        *autoUpdater
+	updatedHandlers    []ToolUpdatedHandler
 }
 
 func (p *API) LoadConfig(path string) (err error) {
-	p.config, err = config.LoadConfig(path)
+	return p.LoadConfigs(path)
+}
+
+// LoadConfigs loads several config files as layers, e.g. a base config, team
+// overrides and local overrides. A tool in a later file replaces the same tool
+// from the earlier ones.
+func (p *API) LoadConfigs(paths ...string) (err error) {
+	conf, err := config.LoadConfigs(paths...)
+	if err != nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.config = conf
+	p.configPaths = append([]string(nil), paths...)
+	p.configLayers = nil
+	p.toolInstances = make(map[string]Tool)
+	return
+}
+
+// MergeConfig merges conf on top of the loaded config, see config.Config.Merge.
+// The layer is kept when the config files are reloaded.
+func (p *API) MergeConfig(conf config.Config) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for key := range p.toolInstances {
+		toolName, _, _ := strings.Cut(key, "@")
+		if _, ok := conf.VersionConfigs[toolName]; ok {
+			delete(p.toolInstances, key)
+		}
+	}
+	p.config.Merge(conf)
+	p.configLayers = append(p.configLayers, conf)
+}
+
+func (p *API) GetConfig() config.Config {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.config
+}
+
+// ToolNames returns the names of all configured tools, sorted.
+func (p *API) ToolNames() []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.sortedToolNames()
+}
+
+func (p *API) sortedToolNames() []string {
+	toolNames := make([]string, 0, len(p.config.ToolConfigs))
+	for toolName := range p.config.ToolConfigs {
+		toolNames = append(toolNames, toolName)
+	}
+	sort.Strings(toolNames)
+	return toolNames
+}
+
+// GetAllTools returns one tool per configured entry, sorted by name. Dev
+// overrides are applied the same way as in GetTool.
+func (p *API) GetAllTools() (tools []Tool, err error) {
+	p.lock.RLock()
+	loaded := p.config.ToolConfigs != nil
+	p.lock.RUnlock()
+	if !loaded {
+		err = fmt.Errorf("config is not loaded")
+		return
+	}
+
+	for _, toolName := range p.ToolNames() {
+		tool, err := p.GetTool(toolName)
+		if err != nil {
+			return nil, err
+		}
+		if tool != nil {
+			tools = append(tools, tool)
+		}
+	}
 	return
 }
 
+// GetTool returns the tool for the version locked by the lockfile, or the
+// version pinned by the project pin file, or else the configured version.
 func (p *API) GetTool(toolName string) (tool Tool, err error) {
+	if locked, lockPath, ok := p.GetLockedVersion(toolName); ok {
+		tool, err = p.getLockedTool(toolName, locked)
+		if err != nil {
+			err = fmt.Errorf("%w (locked in %s)", err, lockPath)
+		}
+		return
+	}
+	if version, pinPath := p.GetPinnedVersion(toolName); version != "" {
+		p.lock.RLock()
+		toolConfig := p.config.ToolConfigs[toolName]
+		p.lock.RUnlock()
+		if toolConfig == nil || toolConfig.Version != version {
+			tool, err = p.GetToolWithVersion(toolName, version)
+			if err != nil {
+				err = fmt.Errorf("%w (pinned in %s)", err, pinPath)
+			}
+			return
+		}
+	}
+	return p.getConfiguredTool(toolName)
+}
+
+func (p *API) getConfiguredTool(toolName string) (tool Tool, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// dev overrides are not cached so they can be changed at runtime
+	toolConfig := p.config.ToolConfigs[toolName]
+	version := ""
+	if toolConfig != nil {
+		version = toolConfig.Version
+	}
+	if override, ok := GetDevToolOverrideForVersion(toolName, version); ok {
+		tool = NewDevTool(toolName, override, toolConfig)
+		return
+	}
+
 	var ok bool
 	if tool, ok = p.toolInstances[toolName]; ok && tool != nil {
 		return
@@ -58,10 +297,365 @@ func (p *API) GetTool(toolName string) (tool Tool, err error) {
 	return
 }
 
-func init() {
-	instance = &API{
+// GetToolWithVersion returns the tool for a specific version. Versions that are
+// not configured can only be used when they are already installed.
+func (p *API) GetToolWithVersion(toolName, version string) (tool Tool, err error) {
+	p.lock.RLock()
+	toolConfig, ok := p.config.ToolConfigs[toolName]
+	versionConfig, configured := p.config.GetVersionConfig(toolName, version)
+	p.lock.RUnlock()
+
+	if !ok {
+		if override, ok := GetDevToolOverrideForVersion(toolName, version); ok {
+			tool = NewDevTool(toolName, override, nil)
+			return
+		}
+		err = fmt.Errorf("tool %s not found in config", toolName)
+		return
+	}
+	if toolConfig.Version == version {
+		return p.getConfiguredTool(toolName)
+	}
+	if configured {
+		return p.getVersionTool(versionConfig)
+	}
+
+	installedConfig := *toolConfig
+	installedConfig.Version = version
+	if override, ok := GetDevToolOverrideForVersion(toolName, version); ok {
+		tool = NewDevTool(toolName, override, &installedConfig)
+		return
+	}
+	versionTool := NewDownloadTool(&installedConfig)
+	if !versionTool.DoesToolExist() {
+		err = fmt.Errorf("tool %s version %s is not installed", toolName, version)
+		return
+	}
+	tool = versionTool
+	return
+}
+
+// getVersionTool 返回配置中某个非默认版本的工具，实例以 tool@version 为键缓存
+func (p *API) getVersionTool(toolConfig *config.ToolConfig) (tool Tool, err error) {
+	if override, ok := GetDevToolOverrideForVersion(toolConfig.ToolName, toolConfig.Version); ok {
+		tool = NewDevTool(toolConfig.ToolName, override, toolConfig)
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	key := toolConfig.ToolName + "@" + toolConfig.Version
+	if tool = p.toolInstances[key]; tool == nil {
+		tool = NewDownloadTool(toolConfig)
+		p.toolInstances[key] = tool
+	}
+	return
+}
+
+// 返回配置中属于 channel 的版本，channel 的含义见 config.ChannelIncludes
+func (p *API) configuredVersions(toolName, channel string) (versions []string, err error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	toolVersions, ok := p.config.VersionConfigs[toolName]
+	if !ok {
+		return nil, fmt.Errorf("tool %s not found in config", toolName)
+	}
+	for version, toolConfig := range toolVersions {
+		if config.ChannelIncludes(channel, toolConfig.Channel) {
+			versions = append(versions, version)
+		}
+	}
+	return
+}
+
+// candidateVersions 返回可供选择的版本：通常为配置中属于 channel 的版本，离线模式下
+// 只有已安装的版本，配置中没有的已安装版本视为 stable
+func (p *API) candidateVersions(toolName, channel string) (versions []string, err error) {
+	if !IsOfflineMode() {
+		return p.configuredVersions(toolName, channel)
+	}
+	installed, err := p.GetInstalledVersions(toolName)
+	if err != nil {
+		return
+	}
+	for _, version := range installed {
+		p.lock.RLock()
+		toolConfig, configured := p.config.GetVersionConfig(toolName, version)
+		p.lock.RUnlock()
+		toolChannel := ""
+		if configured {
+			toolChannel = toolConfig.Channel
+		}
+		if config.ChannelIncludes(channel, toolChannel) {
+			versions = append(versions, version)
+		}
+	}
+	return
+}
+
+// GetToolByConstraint returns the highest configured stable version of a tool
+// that satisfies constraint, e.g. ">=8.0.4 <9.0.0". Installed versions are
+// preferred over versions that still have to be downloaded. In offline mode
+// only installed versions are considered.
+func (p *API) GetToolByConstraint(toolName, constraint string) (tool Tool, err error) {
+	versions, err := p.candidateVersions(toolName, config.ChannelStable)
+	if err != nil {
+		return
+	}
+	matched, err := config.MatchVersions(versions, constraint)
+	if err != nil {
+		return
+	}
+	if len(matched) == 0 {
+		if IsOfflineMode() {
+			err = fmt.Errorf("no installed version of tool %s satisfies %s: %w", toolName, constraint, ErrOffline)
+			return
+		}
+		err = fmt.Errorf("no configured version of tool %s satisfies %s", toolName, constraint)
+		return
+	}
+	for _, version := range matched {
+		if tool, err = p.GetToolWithVersion(toolName, version); err != nil {
+			return
+		}
+		if tool.DoesToolExist() {
+			return
+		}
+	}
+	return p.GetToolWithVersion(toolName, matched[0])
+}
+
+// GetToolLatest returns the highest configured version of a tool on channel,
+// which includes the more stable channels. An empty channel is stable, so beta
+// and nightly versions are only returned when asked for. In offline mode it is
+// the highest installed version.
+func (p *API) GetToolLatest(toolName, channel string) (tool Tool, err error) {
+	versions, err := p.candidateVersions(toolName, channel)
+	if err != nil {
+		return
+	}
+	if len(versions) == 0 {
+		if channel == "" {
+			channel = config.ChannelStable
+		}
+		if IsOfflineMode() {
+			err = fmt.Errorf("tool %s has no installed version on channel %s: %w", toolName, channel, ErrOffline)
+			return
+		}
+		err = fmt.Errorf("tool %s has no version on channel %s", toolName, channel)
+		return
+	}
+	latest := versions[0]
+	for _, version := range versions[1:] {
+		if config.CompareVersions(version, latest) > 0 {
+			latest = version
+		}
+	}
+	return p.GetToolWithVersion(toolName, latest)
+}
+
+// Install installs a tool after its dependencies, reporting download progress to
+// callback (may be nil). An empty version installs the default version of the
+// tool, other configured versions are installed by their version. Cancelling
+// ctx aborts the download. Afterwards old versions are removed to keep the tool
+// folder within SetMaxToolFolderSize.
+func (p *API) Install(ctx context.Context, toolName, version string, callback ProgressCallback) error {
+	p.lock.RLock()
+	toolConfig, ok := p.config.ToolConfigs[toolName]
+	_, configured := p.config.GetVersionConfig(toolName, version)
+	p.lock.RUnlock()
+
+	var tool Tool
+	var err error
+	if version == "" || (ok && version == toolConfig.Version) {
+		if version == "" {
+			tool, err = p.GetTool(toolName)
+		} else {
+			tool, err = p.getConfiguredTool(toolName)
+		}
+		if err == nil && tool == nil {
+			err = fmt.Errorf("tool %s not found in config", toolName)
+		}
+	} else if _, overridden := GetDevToolOverrideForVersion(toolName, version); configured || overridden {
+		tool, err = p.GetToolWithVersion(toolName, version)
+	} else {
+		err = fmt.Errorf("version %s of tool %s is not configured", version, toolName)
+	}
+	if err != nil {
+		return err
+	}
+
+	dependencies, err := p.dependencyOrder(tool)
+	if err != nil {
+		return err
+	}
+	for _, dependency := range dependencies {
+		if dependency.DoesToolExist() {
+			continue
+		}
+		if err = installTool(ctx, dependency, callback); err != nil {
+			return fmt.Errorf("failed to install dependency %s %s of %s: %w", dependency.GetMetadata().Name, dependency.GetVersion(), toolName, err)
+		}
+	}
+	if err = installTool(ctx, tool, callback); err != nil {
+		return err
+	}
+	p.enforceMaxSize(toolName, tool.GetVersion())
+	return nil
+}
+
+// 同一工具版本的安装互斥，InstallAll 中多个工具依赖同一个工具时只会安装一次
+var installLocks sync.Map
+
+// lockInstall 锁住 tool@version 的安装，调用方负责 Unlock
+func lockInstall(key string) *sync.Mutex {
+	lock, _ := installLocks.LoadOrStore(key, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex)
+}
+
+func installTool(ctx context.Context, tool Tool, callback ProgressCallback) error {
+	lock := lockInstall(tool.GetMetadata().Name + "@" + tool.GetVersion())
+	defer lock.Unlock()
+
+	if installer, ok := tool.(interface {
+		InstallWithProgress(ctx context.Context, callback ProgressCallback) error
+	}); ok {
+		return installer.InstallWithProgress(ctx, callback)
+	}
+	return tool.InstallContext(ctx)
+}
+
+// InstallInScope installs a tool into the given scope instead of the scope from its config.
+func (p *API) InstallInScope(toolName string, scope Scope) error {
+	tool, err := p.GetTool(toolName)
+	if err != nil {
+		return err
+	}
+	if tool == nil {
+		return fmt.Errorf("tool %s not found in config", toolName)
+	}
+	scoped, ok := tool.(interface{ SetScope(Scope) })
+	if !ok {
+		return fmt.Errorf("tool %s does not support install scopes", toolName)
+	}
+	scoped.SetScope(scope)
+	return tool.Install()
+}
+
+func (p *API) GetInstalledVersions(toolName string) (versions []string, err error) {
+	p.lock.RLock()
+	toolConfig, ok := p.config.ToolConfigs[toolName]
+	p.lock.RUnlock()
+
+	if !ok {
+		err = fmt.Errorf("tool %s not found in config", toolName)
+		return
+	}
+	versions = listInstalledVersions(toolConfig)
+	return
+}
+
+// IsAnyVersionInstalled reports whether any version of a tool is present. It
+// only stats entry paths and complete markers and returns on the first one
+// found.
+func (p *API) IsAnyVersionInstalled(toolName string) bool {
+	p.lock.RLock()
+	toolConfig, ok := p.config.ToolConfigs[toolName]
+	p.lock.RUnlock()
+
+	if override, ok := GetDevToolOverride(toolName); ok {
+		if _, err := os.Stat(override.Path); err == nil {
+			return true
+		}
+	}
+	if !ok {
+		return false
+	}
+
+	// 先检查配置的版本，再逐个检查版本目录，只 stat 入口和完成标记
+	isInstalled := func(version string) bool {
+		versionConfig := *toolConfig
+		versionConfig.Version = version
+		tool := NewBaseTool(&versionConfig)
+		if _, err := os.Stat(tool.GetToolPath()); err != nil {
+			return false
+		}
+		return hasCompleteMarker(filepath.FromSlash(tool.GetToolFolder()))
+	}
+	if isInstalled(toolConfig.Version) {
+		return true
+	}
+	entries, err := os.ReadDir(filepath.Join(NewBaseTool(toolConfig).getRootFolder(), runtime.GOOS, runtime.GOARCH, toolName))
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		// 跳过安装过程中的临时目录
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || entry.Name() == toolConfig.Version {
+			continue
+		}
+		if isInstalled(entry.Name()) {
+			return true
+		}
+	}
+	return false
+}
+
+// 列出磁盘上已安装的某工具的所有版本
+func listInstalledVersions(toolConfig *config.ToolConfig) (versions []string) {
+	entries, err := os.ReadDir(filepath.Join(NewBaseTool(toolConfig).getRootFolder(), runtime.GOOS, runtime.GOARCH, toolConfig.ToolName))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		// 跳过安装过程中的临时目录
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		versionConfig := *toolConfig
+		versionConfig.Version = entry.Name()
+		if NewBaseTool(&versionConfig).DoesToolExist() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.Strings(versions)
+	return
+}
+
+// removeToolFolder 先把工具目录改名为 .trash- 开头的隐藏目录再删除，
+// 删除中途失败时残留的目录不会被当作已安装的版本
+func removeToolFolder(toolFolder string) error {
+	trash := filepath.Join(filepath.Dir(toolFolder), fmt.Sprintf(".trash-%s-%d", filepath.Base(toolFolder), time.Now().UnixNano()))
+	if err := os.Rename(toolFolder, trash); err != nil {
+		return err
+	}
+	return os.RemoveAll(trash)
+}
+
+// New creates an API instance using the settings from env and the settings file.
+func New() *API {
+	return NewWithSettings(settings.Load())
+}
+
+// NewWithSettings creates an API instance and loads the config files from the
+// resolved settings, if set. Several files are separated by os.PathListSeparator.
+func NewWithSettings(r *settings.Resolver) *API {
+	api := &API{
 		toolInstances: make(map[string]Tool),
 	}
+	if configPath, source := r.Lookup(settings.KeyConfig); configPath != "" {
+		if err := api.LoadConfigs(filepath.SplitList(configPath)...); err != nil {
+			log.Printf("failed to load config %s (from %s): %v\n", configPath, source, err)
+		}
+	}
+	return api
+}
+
+func init() {
+	r := settings.Load()
+	ApplySettings(r)
+	instance = NewWithSettings(r)
 }
 
 func Get() *API {
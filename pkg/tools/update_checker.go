@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kira1928/remotetools/pkg/config"
+)
+
+// UpdateAvailableEvent 表示配置中的版本与已安装版本不一致
+type UpdateAvailableEvent struct {
+	ToolName          string
+	InstalledVersions []string
+	LatestVersion     string
+}
+
+type UpdateHandler func(event UpdateAvailableEvent)
+
+type updateChecker struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// OnUpdateAvailable registers a handler called for every new update found by the checker.
+func (p *API) OnUpdateAvailable(handler UpdateHandler) {
+	p.updateLock.Lock()
+	defer p.updateLock.Unlock()
+	p.updateHandlers = append(p.updateHandlers, handler)
+}
+
+// SetAutoInstallUpdates makes the checker install new versions right after reporting them.
+func (p *API) SetAutoInstallUpdates(enabled bool) {
+	p.updateLock.Lock()
+	defer p.updateLock.Unlock()
+	p.autoInstallUpdates = enabled
+}
+
+func (p *API) StartUpdateChecker(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid update check interval: %s", interval)
+	}
+
+	p.updateLock.Lock()
+	defer p.updateLock.Unlock()
+	if p.updateChecker != nil {
+		return fmt.Errorf("update checker is already running")
+	}
+
+	checker := &updateChecker{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	p.updateChecker = checker
+
+	go func() {
+		defer close(checker.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-checker.stop:
+				return
+			case <-ticker.C:
+				if err := p.runUpdateCheck(); err != nil {
+					log.Println("update check failed:", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (p *API) StopUpdateChecker() {
+	p.updateLock.Lock()
+	checker := p.updateChecker
+	p.updateChecker = nil
+	p.updateLock.Unlock()
+
+	if checker == nil {
+		return
+	}
+	close(checker.stop)
+	<-checker.done
+}
+
+// CheckForUpdates reloads the config file (if any) and returns every tool whose
+// configured version is not installed while another version is.
+func (p *API) CheckForUpdates() (events []UpdateAvailableEvent, err error) {
+	if err = p.reloadConfigFiles(); err != nil {
+		return
+	}
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.config.ToolConfigs == nil {
+		err = fmt.Errorf("config is not loaded")
+		return
+	}
+
+	for _, toolName := range p.sortedToolNames() {
+		toolConfig := p.config.ToolConfigs[toolName]
+		installedVersions := listInstalledVersions(toolConfig)
+		if len(installedVersions) == 0 || containsString(installedVersions, toolConfig.Version) {
+			continue
+		}
+		events = append(events, UpdateAvailableEvent{
+			ToolName:          toolName,
+			InstalledVersions: installedVersions,
+			LatestVersion:     toolConfig.Version,
+		})
+	}
+	return
+}
+
+// reloadConfigFiles reloads the config files loaded by LoadConfigs, if any.
+func (p *API) reloadConfigFiles() error {
+	p.lock.RLock()
+	configPaths := p.configPaths
+	p.lock.RUnlock()
+
+	if len(configPaths) == 0 {
+		return nil
+	}
+	return p.reloadConfig(configPaths)
+}
+
+// reloadConfig 重新读取配置文件，仅丢弃版本发生变化的工具实例
+func (p *API) reloadConfig(paths []string) error {
+	conf, err := config.LoadConfigs(paths...)
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, layer := range p.configLayers {
+		conf.Merge(layer)
+	}
+	for toolName, toolConfig := range conf.ToolConfigs {
+		if oldConfig, ok := p.config.ToolConfigs[toolName]; !ok || oldConfig.Version != toolConfig.Version {
+			delete(p.toolInstances, toolName)
+		}
+	}
+	for key := range p.toolInstances {
+		toolName, version, versioned := strings.Cut(key, "@")
+		if versioned {
+			if _, ok := conf.GetVersionConfig(toolName, version); ok {
+				continue
+			}
+		} else if _, ok := conf.ToolConfigs[toolName]; ok {
+			continue
+		}
+		delete(p.toolInstances, key)
+	}
+	p.config = conf
+	return nil
+}
+
+func (p *API) runUpdateCheck() error {
+	events, err := p.CheckForUpdates()
+	if err != nil {
+		return err
+	}
+
+	p.updateLock.Lock()
+	if p.notifiedUpdates == nil {
+		p.notifiedUpdates = make(map[string]string)
+	}
+	var newEvents []UpdateAvailableEvent
+	for _, event := range events {
+		// only report each version once
+		if p.notifiedUpdates[event.ToolName] == event.LatestVersion {
+			continue
+		}
+		p.notifiedUpdates[event.ToolName] = event.LatestVersion
+		newEvents = append(newEvents, event)
+	}
+	handlers := append([]UpdateHandler(nil), p.updateHandlers...)
+	autoInstall := p.autoInstallUpdates
+	p.updateLock.Unlock()
+
+	for _, event := range newEvents {
+		for _, handler := range handlers {
+			handler(event)
+		}
+		if !autoInstall {
+			continue
+		}
+		tool, err := p.GetTool(event.ToolName)
+		if err != nil || tool == nil {
+			log.Printf("failed to get tool %s for auto install: %v\n", event.ToolName, err)
+			continue
+		}
+		if err := tool.Install(); err != nil {
+			log.Printf("failed to auto install %s %s: %v\n", event.ToolName, event.LatestVersion, err)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisign 格式见 https://jedisct1.github.io/minisign/
+//
+//	公钥:  "Ed" | key id (8) | ed25519 公钥 (32)
+//	签名:  "Ed"（直接签名）或 "ED"（签名 BLAKE2b-512 哈希）| key id (8) | 签名 (64)
+//	      之后一行 "trusted comment: ..."，再一行是对 签名 + trusted comment 的全局签名
+const (
+	minisignAlgLegacy    = "Ed"
+	minisignAlgPrehashed = "ED"
+	minisignKeyIDSize    = 8
+	trustedCommentPrefix = "trusted comment: "
+)
+
+type minisignPublicKey struct {
+	keyID [minisignKeyIDSize]byte
+	key   ed25519.PublicKey
+}
+
+func parseMinisignPublicKey(publicKey string) (pk minisignPublicKey, err error) {
+	// the whole .pub file starts with an untrusted comment
+	line := strings.TrimSpace(publicKey)
+	if lines := nonEmptyLines(publicKey); len(lines) > 0 {
+		line = lines[len(lines)-1]
+	}
+	data, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(data) != 2+minisignKeyIDSize+ed25519.PublicKeySize || string(data[:2]) != minisignAlgLegacy {
+		return pk, fmt.Errorf("invalid minisign public key")
+	}
+	copy(pk.keyID[:], data[2:2+minisignKeyIDSize])
+	pk.key = ed25519.PublicKey(data[2+minisignKeyIDSize:])
+	return
+}
+
+// Minisign verifies a minisign signature file of data.
+func Minisign(publicKey string, data io.Reader, signature []byte) error {
+	pk, err := parseMinisignPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	lines := nonEmptyLines(string(signature))
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], trustedCommentPrefix) {
+		return fmt.Errorf("invalid minisign signature file")
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+minisignKeyIDSize+ed25519.SignatureSize {
+		return fmt.Errorf("invalid minisign signature")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("invalid minisign global signature")
+	}
+
+	alg := string(sig[:2])
+	if !bytes.Equal(sig[2:2+minisignKeyIDSize], pk.keyID[:]) {
+		return fmt.Errorf("minisign signature was made with a different key")
+	}
+
+	var message []byte
+	switch alg {
+	case minisignAlgPrehashed:
+		h, _ := blake2b.New512(nil)
+		if _, err = io.Copy(h, data); err != nil {
+			return err
+		}
+		message = h.Sum(nil)
+	case minisignAlgLegacy:
+		if message, err = io.ReadAll(data); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported minisign algorithm: %q", alg)
+	}
+
+	if !ed25519.Verify(pk.key, message, sig[2+minisignKeyIDSize:]) {
+		return fmt.Errorf("minisign signature verification failed")
+	}
+	trustedComment := strings.TrimPrefix(lines[2], trustedCommentPrefix)
+	signedComment := append(append([]byte(nil), sig[2+minisignKeyIDSize:]...), trustedComment...)
+	if !ed25519.Verify(pk.key, signedComment, globalSig) {
+		return fmt.Errorf("minisign trusted comment verification failed")
+	}
+	return nil
+}
+
+func nonEmptyLines(s string) (lines []string) {
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return
+}
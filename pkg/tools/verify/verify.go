@@ -0,0 +1,75 @@
+// Package verify checks detached signatures of downloaded artifacts.
+//
+// Two formats are supported:
+//
+//   - minisign: the public key is the base64 line of a minisign .pub file (the
+//     whole file is accepted too), signatures may be legacy or prehashed
+//   - gpg: the public key is an armored OpenPGP key ring, signatures may be
+//     armored (.asc) or binary (.sig)
+package verify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+const (
+	TypeMinisign = "minisign"
+	TypeGPG      = "gpg"
+)
+
+// DetectType guesses the signature type from the public key.
+func DetectType(publicKey string) string {
+	if strings.Contains(publicKey, "-----BEGIN PGP") {
+		return TypeGPG
+	}
+	return TypeMinisign
+}
+
+// File verifies the signature of the file at path. An empty sigType is
+// detected from the public key.
+func File(sigType, publicKey, path string, signature []byte) error {
+	if strings.TrimSpace(publicKey) == "" {
+		return fmt.Errorf("no public key configured")
+	}
+	if sigType == "" {
+		sigType = DetectType(publicKey)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch sigType {
+	case TypeMinisign:
+		return Minisign(publicKey, f, signature)
+	case TypeGPG:
+		return GPG(publicKey, f, signature)
+	default:
+		return fmt.Errorf("unsupported signature type: %s", sigType)
+	}
+}
+
+// GPG verifies a detached OpenPGP signature of data against an armored key ring.
+func GPG(armoredKeyRing string, data io.Reader, signature []byte) error {
+	keyRing, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armoredKeyRing))
+	if err != nil {
+		return fmt.Errorf("invalid gpg public key: %w", err)
+	}
+	if bytes.Contains(signature, []byte("-----BEGIN PGP SIGNATURE-----")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyRing, data, bytes.NewReader(signature), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyRing, data, bytes.NewReader(signature), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("gpg signature verification failed: %w", err)
+	}
+	return nil
+}
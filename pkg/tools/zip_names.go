@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"archive/zip"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// zip 文件名编码，对应配置项 zipFilenameEncoding
+const (
+	ZipEncodingAuto     = "auto"
+	ZipEncodingCP437    = "cp437"
+	ZipEncodingGBK      = "gbk"
+	ZipEncodingShiftJIS = "shift_jis"
+)
+
+// zipUTF8Flag 是 zip 通用标志位的第 11 位（Language encoding flag），置位时文件名为 UTF-8
+const zipUTF8Flag = 0x800
+
+var zipNameEncodings = map[string]encoding.Encoding{
+	ZipEncodingCP437:    charmap.CodePage437,
+	ZipEncodingGBK:      simplifiedchinese.GB18030,
+	ZipEncodingShiftJIS: japanese.ShiftJIS,
+}
+
+// auto 模式下依次尝试的编码，CP437 能解码任意字节，所以放在最后兜底
+var zipAutoEncodings = []string{ZipEncodingGBK, ZipEncodingShiftJIS, ZipEncodingCP437}
+
+func needsNameDecoding(f *zip.File) bool {
+	return f.Flags&zipUTF8Flag == 0 && !utf8.ValidString(f.Name)
+}
+
+// decodeZipNames returns the file names of an archive decoded to UTF-8. Names
+// flagged as UTF-8 or already valid UTF-8 are kept. In auto mode, one encoding
+// is picked for the whole archive: the first that decodes every name cleanly.
+func decodeZipNames(files []*zip.File, encodingName string) ([]string, error) {
+	names := make([]string, len(files))
+	var raw []int
+	for i, f := range files {
+		names[i] = f.Name
+		if needsNameDecoding(f) {
+			raw = append(raw, i)
+		}
+	}
+	if len(raw) == 0 {
+		return names, nil
+	}
+
+	if encodingName == "" {
+		encodingName = ZipEncodingAuto
+	}
+	candidates := []string{strings.ToLower(encodingName)}
+	if candidates[0] == ZipEncodingAuto {
+		candidates = zipAutoEncodings
+		// GBK 能干净地解码大部分 Shift-JIS 文件名，所以先看 Shift-JIS 解码结果是否像日文
+		if decoded, ok := decodeNames(japanese.ShiftJIS, files, raw); ok && looksJapanese(decoded) {
+			for j, i := range raw {
+				names[i] = decoded[j]
+			}
+			return names, nil
+		}
+	}
+
+	for _, candidate := range candidates {
+		enc, ok := zipNameEncodings[candidate]
+		if !ok {
+			return nil, fmt.Errorf("unsupported zip filename encoding: %s", encodingName)
+		}
+		decoded, ok := decodeNames(enc, files, raw)
+		if !ok && len(candidates) > 1 {
+			continue
+		}
+		for j, i := range raw {
+			names[i] = decoded[j]
+		}
+		return names, nil
+	}
+	return names, nil
+}
+
+func decodeNames(enc encoding.Encoding, files []*zip.File, indexes []int) ([]string, bool) {
+	decoder := enc.NewDecoder()
+	decoded := make([]string, len(indexes))
+	clean := true
+	for j, i := range indexes {
+		name, err := decoder.String(files[i].Name)
+		if err != nil || strings.ContainsRune(name, utf8.RuneError) {
+			clean = false
+		}
+		decoded[j] = name
+	}
+	return decoded, clean
+}
+
+// looksJapanese reports whether the names contain full-width kana and no
+// half-width katakana, which GBK bytes usually turn into when read as Shift-JIS.
+func looksJapanese(names []string) bool {
+	hasKana := false
+	for _, name := range names {
+		for _, r := range name {
+			switch {
+			case r >= 0x3040 && r <= 0x30FF:
+				hasKana = true
+			case r >= 0xFF61 && r <= 0xFF9F:
+				return false
+			}
+		}
+	}
+	return hasKana
+}
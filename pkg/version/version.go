@@ -0,0 +1,94 @@
+// Package version holds the build information injected by build.go, e.g.
+//
+//	go build -ldflags "-X github.com/kira1928/remotetools/pkg/version.Version=v1.2.3"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+	// minisign public key self-update verifies the release checksums with
+	PublicKey = ""
+)
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"buildTime,omitempty"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+func Get() BuildInfo {
+	return BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
+
+const modulePath = "github.com/kira1928/remotetools"
+
+// ModuleInfo describes the remotetools module linked into the running binary.
+// Revision, RevisionTime and Dirty are only known when remotetools is the main
+// module built from a VCS checkout.
+type ModuleInfo struct {
+	Path         string `json:"path"`
+	Version      string `json:"version"`
+	Sum          string `json:"sum,omitempty"`
+	Replaced     bool   `json:"replaced,omitempty"`
+	Revision     string `json:"revision,omitempty"`
+	RevisionTime string `json:"revisionTime,omitempty"`
+	Dirty        bool   `json:"dirty,omitempty"`
+}
+
+// GetModuleInfo reads the module information embedded by the Go toolchain.
+func GetModuleInfo() (info ModuleInfo, ok bool) {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	if bi.Main.Path == modulePath {
+		info = newModuleInfo(&bi.Main)
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Revision = setting.Value
+			case "vcs.time":
+				info.RevisionTime = setting.Value
+			case "vcs.modified":
+				info.Dirty = setting.Value == "true"
+			}
+		}
+		return info, true
+	}
+
+	for _, dep := range bi.Deps {
+		if dep.Path == modulePath {
+			return newModuleInfo(dep), true
+		}
+	}
+	return ModuleInfo{}, false
+}
+
+func newModuleInfo(module *debug.Module) ModuleInfo {
+	info := ModuleInfo{
+		Path:    module.Path,
+		Version: module.Version,
+		Sum:     module.Sum,
+	}
+	if module.Replace != nil {
+		info.Replaced = true
+		info.Version = module.Replace.Version
+		info.Sum = module.Replace.Sum
+	}
+	return info
+}